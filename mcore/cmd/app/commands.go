@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ishua/a3bot6/mcore/internal/functions"
+)
+
+const commandsUsage = `usage: mcore [flags] [command]
+
+commands:
+  serve           run the http api (default)
+  backup <file>   online copy of the sqlite db into file
+  export <file>   write dialogs and tasks as json into file
+  import <file>   load a json export into an empty db`
+
+// runCommand runs a one-shot admin command against the configured storage.
+func runCommand(ctx context.Context, funcMng *functions.Mng, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("wrong arguments\n%s", commandsUsage)
+	}
+	cmd, path := args[0], args[1]
+
+	switch cmd {
+	case "backup":
+		return funcMng.Backup(ctx, path)
+	case "export":
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		err = funcMng.Export(f)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	case "import":
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer f.Close()
+		return funcMng.Import(f)
+	}
+	return fmt.Errorf("unknown command %s\n%s", cmd, commandsUsage)
+}
@@ -1,25 +1,42 @@
 package main
 
 import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
 	"github.com/ishua/a3bot6/mcore/internal/dialogmng"
 	"github.com/ishua/a3bot6/mcore/internal/functions"
+	"github.com/ishua/a3bot6/mcore/internal/jobs"
 	"github.com/ishua/a3bot6/mcore/internal/rest"
 	"github.com/ishua/a3bot6/mcore/internal/routing"
 	"github.com/ishua/a3bot6/mcore/internal/taskmng"
+	"github.com/ishua/a3bot6/mcore/internal/watchdog"
 	"github.com/ishua/a3bot6/mcore/pkg/logger"
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
-	"github.com/ishua/a3bot6/mcore/internal/storage/msqlclient"
 )
 
 type MyConfig struct {
-	HttpPort       string   `default:"8080" usage:"port where start http rest"`
-	Debug          bool     `default:"false" usage:"turn on debug mode"`
-	SqliteFileName string   `default:"sql.db" usage:"path to sqllite db"`
-	Secrets        []string `usage:"secrets for api"`
-	Users          []string `usage:"users bot allowed"`
+	HttpPort        string        `default:"8080" usage:"port where start http rest"`
+	Debug           bool          `default:"false" usage:"turn on debug mode"`
+	Secrets         []string      `usage:"secrets for api"`
+	Users           []string      `usage:"users bot allowed by telegram id or name, they get the admin role"`
+	ShutdownTimeout time.Duration `default:"15s" usage:"how long to wait for in-flight requests on stop"`
+	BackupDir       string        `default:"data/backup" usage:"where the backup endpoint puts db copies"`
+	Access          access.Config
+	Storage         StorageConfig
+	Tasks           taskmng.Config
+	Routing         routing.Config
+	Watchdog        watchdog.Config
+
+	// SqliteFileName is where the file name was before storage.sqlite
+	SqliteFileName string `usage:"deprecated, use storage.sqlite.file_name"`
 }
 
 var (
@@ -48,26 +65,87 @@ func main() {
 		logger.Fatal("no secrets configured")
 	}
 
-	if len(cfg.Users) == 0 {
-		logger.Fatal("no users configured")
-	}
-
 	logger.Infof("starting mcore version: %s", appVersion)
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	//db init
-	db := msqlclient.NewSqlClient(cfg.SqliteFileName)
-	defer db.DbClose()
+	db, err := openStorage(cfg)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
-	taskMng := taskmng.NewTaskMng(db)
+	policy, err := access.New(cfg.Access, cfg.Users, db)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	taskMng, err := taskmng.NewTaskMng(db, cfg.Tasks, policy)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 	dialogMng := dialogmng.NewDialogMng(db)
 	funcMng := functions.NewMng(db)
 
-	router := routing.NewRouter(cfg.Users, dialogMng, taskMng)
+	args := loader.Flags().Args()
+	if len(args) > 0 && args[0] != "serve" {
+		err = runCommand(ctx, funcMng, args)
+		db.DbClose()
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		logger.Infof("%s done", args[0])
+		return
+	}
+
+	if len(cfg.Users) == 0 && len(cfg.Access.Members) == 0 {
+		logger.Info("no users configured, only users in the db have access")
+	}
+	err = policy.Seed()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	router := routing.NewRouter(cfg.Routing, policy, dialogMng, taskMng)
+
+	wd, err := watchdog.New(cfg.Watchdog, cfg.Access.AdminChatId, db, taskMng)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	jobRunner := jobs.NewRunner(ctx)
+	if taskMng.ExpiryEnabled() {
+		jobRunner.Every("expire tasks", cfg.Tasks.ExpireInterval, taskMng.ExpireTasks)
+	}
+	if wd.Enabled() {
+		jobRunner.Every("watchdog", cfg.Watchdog.Interval, wd.Check)
+	}
+
+	server := rest.NewApi("", taskMng, router, funcMng, db, cfg.Debug, cfg.Secrets, cfg.HttpPort, appVersion, cfg.BackupDir)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run()
+	}()
+
+	// waiting signal for stop
+	select {
+	case <-ctx.Done():
+		logger.Info("received stop signal")
+	case err := <-serverErr:
+		if err != nil {
+			logger.Info(err.Error())
+		}
+	}
 
-	server := rest.NewApi("", taskMng, router, funcMng, cfg.Debug, cfg.Secrets, cfg.HttpPort, appVersion)
-	err := server.Run()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
-		logger.Info(err.Error())
+		logger.Infof("server shutdown: %s", err.Error())
 	}
 
+	jobRunner.Stop()
+	db.DbClose()
+	logger.Info("mcore has stopped")
 }
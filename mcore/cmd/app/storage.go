@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/internal/storage/memstore"
+	"github.com/ishua/a3bot6/mcore/internal/storage/msqlclient"
+	"github.com/ishua/a3bot6/mcore/internal/storage/pgclient"
+	"github.com/ishua/a3bot6/mcore/pkg/logger"
+)
+
+const (
+	storageDriverSqlite   = "sqlite"
+	storageDriverPostgres = "postgres"
+	storageDriverMemory   = "memory"
+
+	defaultSqliteFile = "sql.db"
+)
+
+type StorageConfig struct {
+	Driver      string `default:"sqlite" usage:"storage backend: sqlite, postgres or memory"`
+	Sqlite      msqlclient.Config
+	PostgresDsn string `env:"MCORE_POSTGRES_DSN" usage:"postgres connection string, for driver postgres"`
+}
+
+func openStorage(c MyConfig) (storage.Storage, error) {
+	switch c.Storage.Driver {
+	case storageDriverSqlite:
+		sqlite, err := sqliteConfig(c)
+		if err != nil {
+			return nil, err
+		}
+		return msqlclient.NewSqlClient(sqlite), nil
+	case storageDriverPostgres:
+		if c.Storage.PostgresDsn == "" {
+			return nil, fmt.Errorf("storage driver postgres needs postgres_dsn")
+		}
+		return pgclient.NewPgClient(c.Storage.PostgresDsn)
+	case storageDriverMemory:
+		return memstore.NewMemStore(), nil
+	}
+	return nil, fmt.Errorf("unknown storage driver: %s", c.Storage.Driver)
+}
+
+// sqliteConfig keeps configs with the old sqlite_file_name working, the db
+// they point to is not replaced by an empty default one.
+func sqliteConfig(c MyConfig) (msqlclient.Config, error) {
+	sqlite := c.Storage.Sqlite
+	if c.SqliteFileName == "" {
+		return sqlite, nil
+	}
+	if sqlite.FileName != defaultSqliteFile && sqlite.FileName != c.SqliteFileName {
+		return sqlite, fmt.Errorf("sqlite_file_name %s and storage.sqlite.file_name %s differ, keep only storage.sqlite.file_name",
+			c.SqliteFileName, sqlite.FileName)
+	}
+	logger.Infof("sqlite_file_name is deprecated, move it to storage.sqlite.file_name")
+	sqlite.FileName = c.SqliteFileName
+	return sqlite, nil
+}
@@ -0,0 +1,220 @@
+// Package access maps users to roles and roles to the perms
+// commands need. Users live in the db, the config seeds them.
+package access
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// Perm is what a command needs from the user, roles grant perms.
+type Perm string
+
+const (
+	PermBasic     Perm = "basic"
+	PermInbox     Perm = "inbox"
+	PermNotes     Perm = "notes"
+	PermDownloads Perm = "downloads"
+	PermDelete    Perm = "delete"
+	PermFinance   Perm = "finance"
+	PermAdmin     Perm = "admin"
+)
+
+var allPerms = []Perm{PermBasic, PermInbox, PermNotes, PermDownloads, PermDelete, PermFinance, PermAdmin}
+
+const (
+	RoleAdmin = "admin"
+	RoleGuest = "guest"
+)
+
+// defaultRoles are used for roles the config does not define.
+var defaultRoles = map[string][]Perm{
+	RoleAdmin: allPerms,
+	"family":  {PermBasic, PermInbox, PermDownloads},
+	RoleGuest: {PermBasic},
+}
+
+type Config struct {
+	// role name to perms, replaces the default role of the same name
+	Roles map[string][]string `usage:"perms of roles, defaults are admin, family and guest"`
+	// role name to its users, telegram user ids or user names, names only match messages without an id
+	Members map[string][]string `usage:"users of every role"`
+	// command path to the perm it needs, e.g. ds list: delete
+	Commands map[string]string `usage:"override the perm a command needs"`
+	// access requests of unknown users are sent here
+	AdminChatId int64 `usage:"telegram chat for access requests, they are only listed by /users when 0"`
+}
+
+type Policy struct {
+	repo repo
+	// role by telegram user id or by user name from the config
+	users       map[string]string
+	roles       map[string]map[Perm]bool
+	commands    map[string]Perm
+	adminChatId int64
+}
+
+type repo interface {
+	GetUser(id int64) (schema.User, error)
+	AddUser(u schema.User) error
+	UpdateUser(u schema.User) error
+}
+
+// New builds the policy, admins are users of the plain users list
+// that the config gives no role.
+func New(cfg Config, admins []string, repo repo) (*Policy, error) {
+	p := &Policy{
+		repo:        repo,
+		adminChatId: cfg.AdminChatId,
+		users:       map[string]string{},
+		roles:       map[string]map[Perm]bool{},
+		commands:    map[string]Perm{},
+	}
+	for name, perms := range defaultRoles {
+		p.roles[name] = permSet(perms)
+	}
+	for name, names := range cfg.Roles {
+		perms := make([]Perm, 0, len(names))
+		for _, n := range names {
+			perm, err := parsePerm(n)
+			if err != nil {
+				return nil, fmt.Errorf("access role %s: %w", name, err)
+			}
+			perms = append(perms, perm)
+		}
+		p.roles[name] = permSet(perms)
+	}
+
+	for role, users := range cfg.Members {
+		if _, ok := p.roles[role]; !ok {
+			return nil, fmt.Errorf("access members of unknown role %s", role)
+		}
+		for _, user := range users {
+			if other, ok := p.users[user]; ok {
+				return nil, fmt.Errorf("access user %s is in roles %s and %s", user, other, role)
+			}
+			p.users[user] = role
+		}
+	}
+	for _, user := range admins {
+		if _, ok := p.users[user]; !ok {
+			p.users[user] = RoleAdmin
+		}
+	}
+
+	for path, n := range cfg.Commands {
+		perm, err := parsePerm(n)
+		if err != nil {
+			return nil, fmt.Errorf("access command %s: %w", path, err)
+		}
+		p.commands[strings.Join(strings.Fields(path), " ")] = perm
+	}
+	return p, nil
+}
+
+func parsePerm(name string) (Perm, error) {
+	perm := Perm(name)
+	if !slices.Contains(allPerms, perm) {
+		return "", fmt.Errorf("unknown perm %s", name)
+	}
+	return perm, nil
+}
+
+func permSet(perms []Perm) map[Perm]bool {
+	set := map[Perm]bool{}
+	for _, perm := range perms {
+		set[perm] = true
+	}
+	return set
+}
+
+// Seed adds config users listed by id to the db as active. A user the db
+// already has keeps its role and status, unless it only requested access.
+func (p *Policy) Seed() error {
+	for user, role := range p.users {
+		id, err := strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			continue
+		}
+		u, err := p.repo.GetUser(id)
+		if err != nil {
+			return fmt.Errorf("access seed get user %d: %w", id, err)
+		}
+		switch {
+		case u.Id == 0:
+			err = p.repo.AddUser(schema.User{Id: id, Role: role, Status: schema.UserStatusActive})
+		case u.Status == schema.UserStatusRequested:
+			u.Role = role
+			u.Status = schema.UserStatusActive
+			err = p.repo.UpdateUser(u)
+		}
+		if err != nil {
+			return fmt.Errorf("access seed user %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Role returns the role of the user, "" when there is none. A user the db
+// has is decided by the db, the others by the config id. The user name is
+// only matched when there is no id, anyone can take a free user name.
+func (p *Policy) Role(userId int64, userName string) (string, error) {
+	if userId == 0 {
+		if userName == "" {
+			return "", nil
+		}
+		return p.users[userName], nil
+	}
+	u, err := p.repo.GetUser(userId)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "access get user %d: %w", userId, err)
+	}
+	if u.Id != 0 {
+		if u.Status != schema.UserStatusActive {
+			return "", nil
+		}
+		return u.Role, nil
+	}
+	return p.users[strconv.FormatInt(userId, 10)], nil
+}
+
+// Grants reports whether role has perm, no role has no perms.
+func (p *Policy) Grants(role string, perm Perm) bool {
+	return p.roles[role][perm]
+}
+
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
+
+// Roles returns names of all roles in order.
+func (p *Policy) Roles() []string {
+	return slices.Sorted(maps.Keys(p.roles))
+}
+
+// NamedUsers returns config users listed by name with their roles,
+// the db does not know them.
+func (p *Policy) NamedUsers() map[string]string {
+	named := map[string]string{}
+	for user, role := range p.users {
+		if _, err := strconv.ParseInt(user, 10, 64); err != nil {
+			named[user] = role
+		}
+	}
+	return named
+}
+
+func (p *Policy) AdminChatId() int64 {
+	return p.adminChatId
+}
+
+// Commands returns perms the config sets for command paths.
+func (p *Policy) Commands() map[string]Perm {
+	return maps.Clone(p.commands)
+}
@@ -0,0 +1,62 @@
+package access
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage/memstore"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestRole(t *testing.T) {
+	db := memstore.NewMemStore()
+	p, err := New(Config{Members: map[string][]string{"family": {"200", "mom"}}}, []string{"100", "dad"}, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []schema.User{
+		{Id: 300, Role: RoleGuest, Status: schema.UserStatusActive},
+		{Id: 400, Status: schema.UserStatusRequested},
+	} {
+		err = db.AddUser(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.UpdateUser(schema.User{Id: 200, Role: RoleGuest, Status: schema.UserStatusRevoked})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		userId   int64
+		userName string
+		want     string
+	}{
+		{"seeded admin", 100, "", RoleAdmin},
+		{"db decides over config", 200, "", ""},
+		{"db user", 300, "mom", RoleGuest},
+		{"requested", 400, "", ""},
+		{"unknown id takes no name", 500, "mom", ""},
+		{"unknown id", 500, "", ""},
+		{"name without id", 0, "mom", "family"},
+		{"admin name without id", 0, "dad", RoleAdmin},
+		{"unknown name", 0, "stranger", ""},
+		{"nothing", 0, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Role(tt.userId, tt.userName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Role(%d, %q) = %q, want %q", tt.userId, tt.userName, got, tt.want)
+			}
+		})
+	}
+}
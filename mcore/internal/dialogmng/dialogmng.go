@@ -1,6 +1,10 @@
 package dialogmng
 
 import (
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
@@ -14,12 +18,119 @@ func NewDialogMng(repo repo) *DialogMng {
 
 type repo interface {
 	AddDialog(dialog schema.Dialog) (int64, error)
+	GetDialogById(id int64) (schema.Dialog, error)
+	UpdateDialog(d schema.Dialog) error
+	AddDialogRef(chatId int64, messageId int, dialogId int64) error
+	GetDialogIdByRef(chatId int64, messageId int) (int64, error)
+	InTx(fn func(tx storage.Repo) error) error
+}
+
+// inTx runs fn with a DialogMng on the transaction, a dialog read in it
+// can not change until it is saved.
+func (d *DialogMng) inTx(fn func(d *DialogMng) error) error {
+	return d.repo.InTx(func(tx storage.Repo) error {
+		return fn(&DialogMng{repo: storage.TxRepo{Repo: tx}})
+	})
 }
 
 func (d *DialogMng) Create(m schema.Message) (int64, error) {
-	return d.repo.AddDialog(schema.Dialog{
+	m.CreatedAt = time.Now()
+	id, err := d.repo.AddDialog(schema.Dialog{
 		Key:          schema.GenerateKey(m),
 		DialogStatus: schema.DialogStatusBegin,
 		Messages:     []schema.Message{m},
 	})
+	if err != nil {
+		return 0, schema.Errorf(schema.ErrCodeStorageFailure, "dialogMng create: %w", err)
+	}
+	return id, nil
+}
+
+// Receive appends m to the dialog it replies to when that dialog waits for
+// a reply of the same user, otherwise m begins a new dialog.
+func (d *DialogMng) Receive(m schema.Message) (int64, bool, error) {
+	var id int64
+	var isReply bool
+	err := d.inTx(func(d *DialogMng) error {
+		dialog, err := d.waiting(m)
+		if err != nil {
+			return err
+		}
+		if dialog.Id == 0 {
+			id, err = d.Create(m)
+			return err
+		}
+		id, isReply = dialog.Id, true
+		return d.appendMessage(dialog, m)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return id, isReply, nil
+}
+
+// Answer appends m to the waiting dialog it replies to, a pressed button
+// answers only its own question.
+func (d *DialogMng) Answer(m schema.Message) (int64, error) {
+	var id int64
+	err := d.inTx(func(d *DialogMng) error {
+		dialog, err := d.waiting(m)
+		if err != nil {
+			return err
+		}
+		if dialog.Id == 0 {
+			return schema.Localized(schema.ErrCodeInvalidArgument, i18n.QuestionAnswered)
+		}
+		id = dialog.Id
+		return d.appendMessage(dialog, m)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// waiting finds the dialog of the bot message m replies to,
+// the dialog id is 0 unless it waits for a reply of the user of m.
+func (d *DialogMng) waiting(m schema.Message) (schema.Dialog, error) {
+	if m.ReplyToMessageID == 0 {
+		return schema.Dialog{}, nil
+	}
+	id, err := d.repo.GetDialogIdByRef(m.ChatId, m.ReplyToMessageID)
+	if err != nil {
+		return schema.Dialog{}, schema.Errorf(schema.ErrCodeStorageFailure, "dialogMng find replied dialog: %w", err)
+	}
+	if id == 0 {
+		return schema.Dialog{}, nil
+	}
+	dialog, err := d.repo.GetDialogById(id)
+	if err != nil {
+		return schema.Dialog{}, schema.Errorf(schema.ErrCodeStorageFailure, "dialogMng get dialog: %w", err)
+	}
+	if dialog.DialogStatus != schema.DialogStatusWaitReply || dialog.Key != schema.GenerateKey(m) {
+		return schema.Dialog{}, nil
+	}
+	return dialog, nil
+}
+
+func (d *DialogMng) appendMessage(dialog schema.Dialog, m schema.Message) error {
+	m.CreatedAt = time.Now()
+	dialog.Messages = append(dialog.Messages, m)
+	err := d.repo.UpdateDialog(dialog)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "dialogMng append message: %w", err)
+	}
+	return nil
+}
+
+// AddBotMessage remembers a message the bot sent for a dialog.
+func (d *DialogMng) AddBotMessage(req schema.BotMsgReq) error {
+	if req.DialogId == 0 || req.ChatId == 0 || req.MessageId == 0 {
+		return schema.NewError(schema.ErrCodeBadRequest, "botMsg dialogId, chatId and messageId are required")
+	}
+	err := d.repo.AddDialogRef(req.ChatId, req.MessageId, req.DialogId)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "dialogMng add bot message: %w", err)
+	}
+	return nil
 }
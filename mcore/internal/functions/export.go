@@ -0,0 +1,179 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const (
+	exportVersion  = 1
+	exportPageSize = 500
+)
+
+// Export is the portable dump of mcore data, it does not depend on
+// the storage backend.
+type Export struct {
+	Version   int                  `json:"version"`
+	CreatedAt time.Time            `json:"createdAt"`
+	Dialogs   []schema.Dialog      `json:"dialogs"`
+	Tasks     []schema.Task        `json:"tasks"`
+	Users     []schema.User        `json:"users,omitempty"`
+	Settings  []schema.UserSetting `json:"settings,omitempty"`
+	Aliases   []schema.UserAlias   `json:"aliases,omitempty"`
+}
+
+type backuper interface {
+	Backup(ctx context.Context, destPath string) error
+}
+
+// Backup takes a consistent copy of a running db, only sqlite supports it.
+func (mng *Mng) Backup(ctx context.Context, destPath string) error {
+	b, ok := mng.repo.(backuper)
+	if !ok {
+		return schema.NewError(schema.ErrCodeBadRequest, "backup is supported only by the sqlite storage")
+	}
+	err := b.Backup(ctx, destPath)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "backup: %w", err)
+	}
+	return nil
+}
+
+// Export writes all dialogs, tasks, users and their settings as json. It reads in one transaction,
+// so the dump is consistent.
+func (mng *Mng) Export(w io.Writer) error {
+	e := Export{
+		Version:   exportVersion,
+		CreatedAt: time.Now().UTC(),
+		Dialogs:   []schema.Dialog{},
+		Tasks:     []schema.Task{},
+	}
+
+	err := mng.repo.InTx(func(tx storage.Repo) error {
+		var afterId int64
+		for {
+			dd, err := tx.ListDialogs(afterId, exportPageSize)
+			if err != nil {
+				return fmt.Errorf("list dialogs: %w", err)
+			}
+			e.Dialogs = append(e.Dialogs, dd...)
+			if len(dd) < exportPageSize {
+				break
+			}
+			afterId = dd[len(dd)-1].Id
+		}
+
+		afterId = 0
+		for {
+			tt, err := tx.ListTasks(afterId, exportPageSize)
+			if err != nil {
+				return fmt.Errorf("list tasks: %w", err)
+			}
+			e.Tasks = append(e.Tasks, tt...)
+			if len(tt) < exportPageSize {
+				break
+			}
+			afterId = tt[len(tt)-1].Id
+		}
+
+		uu, err := tx.ListUsers()
+		if err != nil {
+			return fmt.Errorf("list users: %w", err)
+		}
+		e.Users = uu
+
+		e.Settings, err = tx.ListAllUserSettings()
+		if err != nil {
+			return fmt.Errorf("list settings: %w", err)
+		}
+
+		e.Aliases, err = tx.ListAllUserAliases()
+		if err != nil {
+			return fmt.Errorf("list aliases: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "export: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(e)
+	if err != nil {
+		return fmt.Errorf("export encode: %w", err)
+	}
+	return nil
+}
+
+// Import loads an export into an empty db, ids and timestamps are kept.
+// Users already in the db, e.g. seeded from the config, stay as they are.
+func (mng *Mng) Import(r io.Reader) error {
+	var e Export
+	err := json.NewDecoder(r).Decode(&e)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeBadRequest, "import decode: %w", err)
+	}
+	if e.Version != exportVersion {
+		return schema.Errorf(schema.ErrCodeBadRequest, "import unsupported export version %d", e.Version)
+	}
+
+	return mng.repo.InTx(func(tx storage.Repo) error {
+		dd, err := tx.ListDialogs(0, 1)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "import list dialogs: %w", err)
+		}
+		tt, err := tx.ListTasks(0, 1)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "import list tasks: %w", err)
+		}
+		if len(dd) > 0 || len(tt) > 0 {
+			return schema.NewError(schema.ErrCodeBadRequest, "import needs an empty db")
+		}
+
+		for _, d := range e.Dialogs {
+			err = tx.RestoreDialog(d)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import dialog %d: %w", d.Id, err)
+			}
+		}
+		for _, t := range e.Tasks {
+			err = tx.RestoreTask(t)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import task %d: %w", t.Id, err)
+			}
+		}
+		for _, u := range e.Users {
+			saved, err := tx.GetUser(u.Id)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import get user %d: %w", u.Id, err)
+			}
+			if saved.Id != 0 {
+				continue
+			}
+			err = tx.AddUser(u)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import user %d: %w", u.Id, err)
+			}
+		}
+		for _, st := range e.Settings {
+			err = tx.SetUserSetting(st.UserId, st.Key, st.Value)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import setting %s of %d: %w", st.Key, st.UserId, err)
+			}
+		}
+		for _, a := range e.Aliases {
+			err = tx.SetUserAlias(a.UserId, a.Name, a.Expansion)
+			if err != nil {
+				return schema.Errorf(schema.ErrCodeStorageFailure, "import alias %s of %d: %w", a.Name, a.UserId, err)
+			}
+		}
+		return nil
+	})
+}
@@ -1,6 +1,9 @@
 package functions
 
-import "fmt"
+import (
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
 
 type Mng struct {
 	repo repo
@@ -9,6 +12,9 @@ type Mng struct {
 type repo interface {
 	DeleteAllTasks() error
 	DeleteAllDialogs() error
+	ListTasks(afterId int64, limit int) ([]schema.Task, error)
+	ListDialogs(afterId int64, limit int) ([]schema.Dialog, error)
+	InTx(fn func(tx storage.Repo) error) error
 }
 
 func NewMng(repo repo) *Mng {
@@ -16,14 +22,16 @@ func NewMng(repo repo) *Mng {
 }
 
 func (mng *Mng) DeleteAll() error {
-	err := mng.repo.DeleteAllTasks()
-	if err != nil {
-		return fmt.Errorf("deleteAll tasks Error: %s", err.Error())
-	}
+	return mng.repo.InTx(func(tx storage.Repo) error {
+		err := tx.DeleteAllTasks()
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "deleteAll tasks Error: %w", err)
+		}
 
-	err = mng.repo.DeleteAllDialogs()
-	if err != nil {
-		return fmt.Errorf("deleteAll dialogs Error: %s", err.Error())
-	}
-	return nil
+		err = tx.DeleteAllDialogs()
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "deleteAll dialogs Error: %w", err)
+		}
+		return nil
+	})
 }
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/logger"
+)
+
+// Runner runs periodic background jobs until it is stopped.
+type Runner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(ctx context.Context) *Runner {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Runner{ctx: ctx, cancel: cancel}
+}
+
+// Every starts fn in the background and repeats it every interval.
+func (r *Runner) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.ctx.Done():
+				logger.Infof("job %s stopped", name)
+				return
+			case <-ticker.C:
+				err := fn(r.ctx)
+				if err != nil {
+					logger.Infof("job %s err: %s", name, err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// Stop cancels all jobs and waits for the running ones to return.
+func (r *Runner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
@@ -1,11 +1,17 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ishua/a3bot6/mcore/pkg/logger"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type Api struct {
@@ -13,98 +19,134 @@ type Api struct {
 	taskMng    taskMnger
 	router     router
 	funcMng    funcMng
+	checker    readyChecker
 	debug      bool
 	secrets    []string
 	port       string
 	appVersion string
+	backupDir  string
+	server     *http.Server
 }
 
 type taskMnger interface {
-	GetTask(taskType schema.TaskType) (schema.Task, error)
-	ReportTask(taskId int64, status schema.TaskStatus, textMsg string) error
+	GetTask(taskType schema.TaskType, worker string) (schema.Task, error)
+	ReportTask(rt schema.ReportTaskReq) error
+	CancelTask(taskId int64, reason string) error
+	Stats(since time.Time) (schema.TaskStats, error)
 }
 type funcMng interface {
 	DeleteAll() error
+	Backup(ctx context.Context, destPath string) error
+	Export(w io.Writer) error
 }
 
 type router interface {
-	ProcessMsg(m schema.Message) schema.TaskMsg
+	ProcessMsg(m schema.Message) (schema.TaskMsg, int64, error)
+	ProcessBotMsg(req schema.BotMsgReq) error
+	ProcessCallback(cb schema.CallbackReq) (schema.TaskMsg, int64, error)
 }
 
-func NewApi(rootPath string, taskMng taskMnger, router router, funcMng funcMng, debug bool, secrets []string, port string, appVersion string) *Api {
+type readyChecker interface {
+	Ready(ctx context.Context) error
+}
+
+func NewApi(rootPath string, taskMng taskMnger, router router, funcMng funcMng, checker readyChecker, debug bool, secrets []string, port string, appVersion string, backupDir string) *Api {
 	return &Api{
 		rootPath:   rootPath,
 		taskMng:    taskMng,
 		router:     router,
 		funcMng:    funcMng,
+		checker:    checker,
 		debug:      debug,
 		secrets:    secrets,
 		port:       port,
 		appVersion: appVersion,
+		backupDir:  backupDir,
 	}
 }
 
-type ErrorRes struct {
-	Error  string `json:"error"`
-	Status string `json:"status"`
+// Run serves http until Shutdown is called.
+func (a *Api) Run() error {
+	a.server = &http.Server{
+		Addr:              ":" + a.port,
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.Info("start server port:" + a.port)
+	err := a.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func (a *Api) Run() error {
+// Shutdown stops accepting connections and waits for in-flight requests.
+func (a *Api) Shutdown(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+	return a.server.Shutdown(ctx)
+}
+
+func (a *Api) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	getTaskLink := fmt.Sprintf("%s/get-task/", a.rootPath)
 	reportTaskLink := fmt.Sprintf("%s/report-task/", a.rootPath)
 	addMsgLink := fmt.Sprintf("%s/add-msg/", a.rootPath)
+	botMsgLink := fmt.Sprintf("%s/bot-msg/", a.rootPath)
+	callbackLink := fmt.Sprintf("%s/callback/", a.rootPath)
 
 	mux.HandleFunc("POST "+getTaskLink, a.HandlerGetTask)
 	mux.HandleFunc("POST "+reportTaskLink, a.HandlerReportTask)
 	mux.HandleFunc("POST "+addMsgLink, a.HandlerAddMsg)
+	mux.HandleFunc("POST "+botMsgLink, a.HandlerBotMsg)
+	mux.HandleFunc("POST "+callbackLink, a.HandlerCallback)
+	mux.HandleFunc("POST /cancel-task/", a.HandlerCancelTask)
+	mux.HandleFunc("GET /stats/", a.HandlerStats)
 	mux.HandleFunc("GET /health/", a.HandlerHealth)
+	mux.HandleFunc("GET /ready/", a.HandlerReady)
 	mux.HandleFunc("POST /delete-all-data/", a.HandlerDeleteAllData)
+	mux.HandleFunc("POST /backup/", a.HandlerBackup)
+	mux.HandleFunc("GET /export/", a.HandlerExport)
 
 	var h http.Handler
 	h = mux
 	if a.debug {
 		h = middleLog(h)
 	}
-	h = middleAuth(h, a.secrets)
-
-	logger.Info("start server port:" + a.port)
-	return http.ListenAndServe(":"+a.port, h)
+	return middleAuth(h, a.secrets)
 }
 
 func (a *Api) HandlerGetTask(w http.ResponseWriter, req *http.Request) {
 	var taskReq schema.GetTaskReq
 	err := json.NewDecoder(req.Body).Decode(&taskReq)
 	if err != nil {
-		getErrResp(w, fmt.Errorf("body GetTask decode err: %w", err))
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body GetTask decode err: %w", err))
 		return
 	}
 
-	task, err := a.taskMng.GetTask(taskReq.TaskType)
+	task, err := a.taskMng.GetTask(taskReq.TaskType, taskReq.Worker)
 	if err != nil {
 		getErrResp(w, fmt.Errorf("getTask err: %w", err))
 		return
 	}
 
-	status := "OK"
 	if task.Id == 0 {
-		status = "no tasks"
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
 
 	b, err := json.Marshal(schema.GetTaskRes{
 		Data:   task,
-		Status: status,
+		Status: "OK",
 	})
 	if err != nil {
 		getErrResp(w, fmt.Errorf("response GetTask decode err: %w", err))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(b)
-	if err != nil {
-		logger.Fatalf("HandlerGetTask can not write answer %s", err.Error())
-	}
+	writeResp(w, req, b)
 
 }
 
@@ -112,11 +154,11 @@ func (a *Api) HandlerReportTask(w http.ResponseWriter, req *http.Request) {
 	var rt schema.ReportTaskReq
 	err := json.NewDecoder(req.Body).Decode(&rt)
 	if err != nil {
-		getErrResp(w, fmt.Errorf("body ReportTask decode err: %w", err))
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body ReportTask decode err: %w", err))
 		return
 	}
 
-	err = a.taskMng.ReportTask(rt.TaskId, rt.Status, rt.TextMsg)
+	err = a.taskMng.ReportTask(rt)
 	if err != nil {
 		getErrResp(w, fmt.Errorf("reportTask err: %w", err))
 		return
@@ -129,12 +171,61 @@ func (a *Api) HandlerReportTask(w http.ResponseWriter, req *http.Request) {
 		getErrResp(w, fmt.Errorf("response reportTask decode err: %w", err))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(b)
+	writeResp(w, req, b)
+
+}
+
+func (a *Api) HandlerCancelTask(w http.ResponseWriter, req *http.Request) {
+	var ct schema.CancelTaskReq
+	err := json.NewDecoder(req.Body).Decode(&ct)
+	if err != nil {
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body CancelTask decode err: %w", err))
+		return
+	}
+
+	err = a.taskMng.CancelTask(ct.TaskId, ct.Reason)
+	if err != nil {
+		getErrResp(w, fmt.Errorf("cancelTask err: %w", err))
+		return
+	}
+
+	b, err := json.Marshal(schema.Req{
+		Status: "OK",
+	})
+	if err != nil {
+		getErrResp(w, fmt.Errorf("response cancelTask decode err: %w", err))
+		return
+	}
+	writeResp(w, req, b)
+}
+
+// HandlerStats reports task durations and failures, ?days= sets the window.
+func (a *Api) HandlerStats(w http.ResponseWriter, req *http.Request) {
+	days := 7
+	if v := req.URL.Query().Get("days"); v != "" {
+		var err error
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 {
+			getErrResp(w, schema.NewError(schema.ErrCodeBadRequest, "stats days must be a positive number"))
+			return
+		}
+	}
+
+	st, err := a.taskMng.Stats(time.Now().AddDate(0, 0, -days))
 	if err != nil {
-		logger.Fatalf("HandlerReportTask can not write answer %s", err.Error())
+		getErrResp(w, fmt.Errorf("stats err: %w", err))
+		return
 	}
 
+	b, err := json.Marshal(schema.TaskStatsRes{
+		Data:   st,
+		Status: "OK",
+	})
+	if err != nil {
+		getErrResp(w, fmt.Errorf("response stats decode err: %w", err))
+		return
+	}
+	writeResp(w, req, b)
 }
 
 func (a *Api) HandlerAddMsg(w http.ResponseWriter, req *http.Request) {
@@ -142,36 +233,95 @@ func (a *Api) HandlerAddMsg(w http.ResponseWriter, req *http.Request) {
 
 	err := json.NewDecoder(req.Body).Decode(&m)
 	if err != nil {
-		getErrResp(w, fmt.Errorf("body addMsg decode err: %w", err))
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body addMsg decode err: %w", err))
 		return
 	}
 
-	if m.ChatId == 0 || m.UserName == "" {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	if m.ChatId == 0 || (m.UserId == 0 && m.UserName == "") {
+		getErrResp(w, schema.NewError(schema.ErrCodeBadRequest, "addMsg chatId and userId or userName are required"))
 		return
 	}
 
-	t := a.router.ProcessMsg(m)
-
-	var res schema.AddMsgReq
-	res.Status = "OK"
+	t, dialogId, err := a.router.ProcessMsg(m)
+	if err != nil {
+		// the text goes back to the user, so it is not wrapped
+		getErrResp(w, err)
+		return
+	}
 
 	b, err := json.Marshal(schema.AddMsgReq{
-		Data:   t,
-		Status: "OK",
+		Data:     t,
+		DialogId: dialogId,
+		Status:   "OK",
 	})
 	if err != nil {
 		getErrResp(w, fmt.Errorf("response addMsg decode err: %w", err))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(b)
+	writeResp(w, req, b)
+}
+
+// HandlerCallback routes a pressed inline button into the dialog
+// of its message and answers like add-msg.
+func (a *Api) HandlerCallback(w http.ResponseWriter, req *http.Request) {
+	var cb schema.CallbackReq
+	err := json.NewDecoder(req.Body).Decode(&cb)
+	if err != nil {
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body callback decode err: %w", err))
+		return
+	}
+
+	if cb.ChatId == 0 || (cb.UserId == 0 && cb.UserName == "") || cb.MessageId == 0 {
+		getErrResp(w, schema.NewError(schema.ErrCodeBadRequest, "callback chatId, userId or userName and messageId are required"))
+		return
+	}
+
+	t, dialogId, err := a.router.ProcessCallback(cb)
+	if err != nil {
+		// the text goes back to the user, so it is not wrapped
+		getErrResp(w, err)
+		return
+	}
+
+	b, err := json.Marshal(schema.AddMsgReq{
+		Data:     t,
+		DialogId: dialogId,
+		Status:   "OK",
+	})
+	if err != nil {
+		getErrResp(w, fmt.Errorf("response callback decode err: %w", err))
+		return
+	}
+	writeResp(w, req, b)
+}
+
+// HandlerBotMsg links a telegram message of the bot to its dialog,
+// a reply to it continues the dialog.
+func (a *Api) HandlerBotMsg(w http.ResponseWriter, req *http.Request) {
+	var bm schema.BotMsgReq
+	err := json.NewDecoder(req.Body).Decode(&bm)
+	if err != nil {
+		getErrResp(w, schema.Errorf(schema.ErrCodeBadRequest, "body botMsg decode err: %w", err))
+		return
+	}
+
+	err = a.router.ProcessBotMsg(bm)
+	if err != nil {
+		getErrResp(w, fmt.Errorf("botMsg err: %w", err))
+		return
+	}
+
+	b, err := json.Marshal(schema.Req{
+		Status: "OK",
+	})
 	if err != nil {
-		logger.Fatalf("addMsg can not write answer %s", err.Error())
+		getErrResp(w, fmt.Errorf("response botMsg decode err: %w", err))
+		return
 	}
+	writeResp(w, req, b)
 }
 
-func (a *Api) HandlerDeleteAllData(w http.ResponseWriter, _ *http.Request) {
+func (a *Api) HandlerDeleteAllData(w http.ResponseWriter, req *http.Request) {
 	err := a.funcMng.DeleteAll()
 	if err != nil {
 		getErrResp(w, fmt.Errorf("deleteAll err: %w", err))
@@ -184,35 +334,114 @@ func (a *Api) HandlerDeleteAllData(w http.ResponseWriter, _ *http.Request) {
 		getErrResp(w, fmt.Errorf("response reportTask decode err: %w", err))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(b)
+	writeResp(w, req, b)
+
+}
+
+// HandlerBackup writes an online copy of the db into the backup dir
+// and answers with the file path.
+func (a *Api) HandlerBackup(w http.ResponseWriter, req *http.Request) {
+	path := filepath.Join(a.backupDir, "sql-"+time.Now().Format("20060102-150405")+".db")
+	err := a.funcMng.Backup(req.Context(), path)
+	if err != nil {
+		getErrResp(w, fmt.Errorf("backup err: %w", err))
+		return
+	}
+	b, err := json.Marshal(schema.BackupRes{
+		Path:   path,
+		Status: "OK",
+	})
 	if err != nil {
-		logger.Fatalf("HandlerReportTask can not write answer %s", err.Error())
+		getErrResp(w, fmt.Errorf("response backup decode err: %w", err))
+		return
+	}
+	writeResp(w, req, b)
+}
+
+// HandlerExport streams the export into the response, an error after
+// the first write can only be logged.
+func (a *Api) HandlerExport(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", "attachment; filename=mcore-export.json")
+	ew := &exportWriter{w: w}
+	err := a.funcMng.Export(ew)
+	if err == nil {
+		return
+	}
+	if ew.started {
+		logger.Infof("%s export broken off %s", req.URL.Path, err.Error())
+		return
 	}
+	w.Header().Del("Content-Disposition")
+	getErrResp(w, fmt.Errorf("export err: %w", err))
+}
 
+// exportWriter tells whether the response has begun.
+type exportWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (ew *exportWriter) Write(p []byte) (int, error) {
+	ew.started = true
+	return ew.w.Write(p)
 }
 
 func getErrResp(w http.ResponseWriter, err error) {
 	logger.Info("handler: " + err.Error())
-	b, err := json.Marshal(ErrorRes{
+	code := schema.CodeOf(err)
+	b, err := json.Marshal(schema.ErrorRes{
 		Error:  err.Error(),
+		Code:   code,
 		Status: "error",
 	})
 	if err != nil {
-		logger.Fatalf("http handler can not marshal an error %s", err.Error())
+		logger.Infof("http handler can not marshal an error %s", err.Error())
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(code))
 	_, err = w.Write(b)
 	if err != nil {
-		logger.Fatalf("http handler cannot return an error %s", err.Error())
+		logger.Infof("http handler cannot return an error %s", err.Error())
+	}
+}
+
+func httpStatus(code schema.ErrorCode) int {
+	switch code {
+	case schema.ErrCodeBadRequest, schema.ErrCodeInvalidArgument:
+		return http.StatusBadRequest
+	case schema.ErrCodeUnauthorized:
+		return http.StatusUnauthorized
+	case schema.ErrCodeUnauthorizedUser, schema.ErrCodeNotPermitted:
+		return http.StatusForbidden
+	case schema.ErrCodeNotFound:
+		return http.StatusNotFound
+	case schema.ErrCodeUnknownCommand:
+		return http.StatusUnprocessableEntity
+	case schema.ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	case schema.ErrCodeStorageFailure:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeResp writes a json answer. A failed write only concerns the current
+// request, so it is logged and the server keeps running.
+func writeResp(w http.ResponseWriter, req *http.Request, b []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(b)
+	if err != nil {
+		logger.Infof("%s can not write answer %s", req.URL.Path, err.Error())
 	}
-	return
 }
 
 func middleAuth(next http.Handler, secrets []string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		url := r.URL
-		if url.Path == "/health/" || url.Path == "/health" {
+		if url.Path == "/health/" || url.Path == "/health" || url.Path == "/ready/" || url.Path == "/ready" {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -223,7 +452,7 @@ func middleAuth(next http.Handler, secrets []string) http.Handler {
 				return
 			}
 		}
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		getErrResp(w, schema.NewError(schema.ErrCodeUnauthorized, "wrong secret for "+url.Path))
 	})
 }
 
@@ -234,9 +463,34 @@ func (a *Api) HandlerHealth(w http.ResponseWriter, req *http.Request) {
 	}
 
 	js, _ := json.Marshal(PingRes{Status: "OK", Version: a.appVersion})
-	w.Header().Set("Content-Type", "application/json")
-	_, err := w.Write(js)
+	writeResp(w, req, js)
+}
+
+// HandlerReady reports whether mcore can serve traffic: the db answers and
+// its schema is in place. Unlike health it fails while storage is broken.
+func (a *Api) HandlerReady(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), 2*time.Second)
+	defer cancel()
+
+	err := a.checker.Ready(ctx)
 	if err != nil {
-		logger.Fatalf("health can not write answer %s", err.Error())
+		logger.Info("ready: " + err.Error())
+		b, _ := json.Marshal(schema.ErrorRes{
+			Error:  err.Error(),
+			Code:   schema.ErrCodeStorageFailure,
+			Status: "not ready",
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, err = w.Write(b)
+		if err != nil {
+			logger.Infof("ready can not write answer %s", err.Error())
+		}
+		return
 	}
+
+	b, _ := json.Marshal(schema.Req{
+		Status: "OK",
+	})
+	writeResp(w, req, b)
 }
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+type exportFunc func(w io.Writer) error
+
+func (f exportFunc) DeleteAll() error                                  { return nil }
+func (f exportFunc) Backup(ctx context.Context, destPath string) error { return nil }
+func (f exportFunc) Export(w io.Writer) error                          { return f(w) }
+
+func TestHandlerExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		export     exportFunc
+		wantStatus int
+		wantBody   string
+		attachment bool
+	}{
+		{
+			name:       "ok",
+			export:     func(w io.Writer) error { _, err := io.WriteString(w, `{"version":1}`); return err },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"version":1}`,
+			attachment: true,
+		},
+		{
+			name:       "fails before writing",
+			export:     func(w io.Writer) error { return schema.NewError(schema.ErrCodeStorageFailure, "db gone") },
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `"status":"error"`,
+		},
+		{
+			name: "fails after writing",
+			export: func(w io.Writer) error {
+				fmt.Fprint(w, `{"version":`)
+				return errors.New("encode")
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"version":`,
+			attachment: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Api{funcMng: tt.export}
+			rec := httptest.NewRecorder()
+			a.HandlerExport(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want %q in it", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Disposition") != ""; got != tt.attachment {
+				t.Errorf("attachment = %v, want %v", got, tt.attachment)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("content type = %q", ct)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// RateLimit is the average number of messages and pressed buttons a user
+	// may send per minute, 0 turns the limit off
+	RateLimit float64 `default:"30" usage:"messages a user may send per minute"`
+	RateBurst int     `default:"10" usage:"messages a user may send at once"`
+}
+
+// limiter keeps a token bucket per user, a message takes a token and
+// tokens come back at the rate.
+type limiter struct {
+	mu      sync.Mutex
+	perSec  float64
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+	// refill is how long an empty bucket takes to become full
+	refill time.Duration
+	swept  time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(cfg Config) *limiter {
+	if cfg.RateLimit <= 0 {
+		return nil
+	}
+	l := &limiter{
+		perSec:  cfg.RateLimit / 60,
+		burst:   math.Max(float64(cfg.RateBurst), 1),
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+	l.refill = time.Duration(l.burst / l.perSec * float64(time.Second))
+	return l
+}
+
+// allow takes a token of the user, when there is none it returns how long
+// to wait for one. A nil limiter allows everything.
+func (l *limiter) allow(user string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[user]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[user] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSec)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	secs := math.Ceil((1 - b.tokens) / l.perSec)
+	return time.Duration(secs) * time.Second, false
+}
+
+// sweep drops buckets idle for the refill time once per refill time, they
+// are full again and a new bucket is the same.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.refill {
+		return
+	}
+	l.swept = now
+	for user, b := range l.buckets {
+		if now.Sub(b.last) >= l.refill {
+			delete(l.buckets, user)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	// 60 a minute is a token a second, a burst of 3
+	cfg := Config{RateLimit: 60, RateBurst: 3}
+	tests := []struct {
+		name  string
+		steps []time.Duration // time since start of every message
+		want  []bool
+		wait  time.Duration // wait of the last message
+	}{
+		{"burst", []time.Duration{0, 0, 0}, []bool{true, true, true}, 0},
+		{"over burst", []time.Duration{0, 0, 0, 0}, []bool{true, true, true, false}, time.Second},
+		{"refills at rate", []time.Duration{0, 0, 0, time.Second}, []bool{true, true, true, true}, 0},
+		{"half a token", []time.Duration{0, 0, 0, 500 * time.Millisecond, 500 * time.Millisecond}, []bool{true, true, true, false, false}, time.Second},
+		{"no more than burst", []time.Duration{0, time.Hour, time.Hour, time.Hour, time.Hour}, []bool{true, true, true, true, false}, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			var now time.Time
+			l := newLimiter(cfg)
+			l.now = func() time.Time { return now }
+			var wait time.Duration
+			for i, step := range tt.steps {
+				now = start.Add(step)
+				var ok bool
+				wait, ok = l.allow("u")
+				if ok != tt.want[i] {
+					t.Fatalf("message %d allowed %v, want %v", i, ok, tt.want[i])
+				}
+			}
+			if wait != tt.wait {
+				t.Errorf("wait = %s, want %s", wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestLimiterUsers(t *testing.T) {
+	l := newLimiter(Config{RateLimit: 60, RateBurst: 1})
+	if _, ok := l.allow("a"); !ok {
+		t.Fatal("first message of a refused")
+	}
+	if _, ok := l.allow("a"); ok {
+		t.Fatal("second message of a allowed")
+	}
+	if _, ok := l.allow("b"); !ok {
+		t.Fatal("b shares the bucket of a")
+	}
+}
+
+func TestLimiterOff(t *testing.T) {
+	l := newLimiter(Config{RateLimit: 0, RateBurst: 1})
+	if l != nil {
+		t.Fatal("want no limiter without a rate")
+	}
+	for range 100 {
+		if _, ok := l.allow("u"); !ok {
+			t.Fatal("a nil limiter refused")
+		}
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	// an empty bucket is full again after 2 seconds
+	l := newLimiter(Config{RateLimit: 60, RateBurst: 2})
+	start := time.Now()
+	now := start
+	l.now = func() time.Time { return now }
+	if l.refill != 2*time.Second {
+		t.Fatalf("refill = %s, want 2s", l.refill)
+	}
+
+	l.allow("idle")
+	now = start.Add(time.Second)
+	l.allow("busy")
+	now = start.Add(2 * time.Second)
+	l.allow("busy")
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket is kept after the refill time")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("busy bucket is dropped")
+	}
+
+	// the next sweep waits for the refill time
+	now = start.Add(3500 * time.Millisecond)
+	l.allow("other")
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("swept before the refill time")
+	}
+	now = start.Add(4500 * time.Millisecond)
+	l.allow("other")
+	if _, ok := l.buckets["busy"]; ok {
+		t.Error("busy bucket is kept after the refill time")
+	}
+}
@@ -2,50 +2,186 @@ package routing
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
-	"slices"
 )
 
 type Router struct {
-	allowedUsers []string
-	dialogMng    dialogMng
-	taskMng      taskMng
+	users     users
+	dialogMng dialogMng
+	taskMng   taskMng
+	limiter   *limiter
+}
+
+// users knows who has a role, what a role permits is checked by commands.
+type users interface {
+	Role(userId int64, userName string) (string, error)
 }
 
 type dialogMng interface {
-	Create(m schema.Message) (int64, error)
+	Receive(m schema.Message) (int64, bool, error)
+	Answer(m schema.Message) (int64, error)
+	AddBotMessage(req schema.BotMsgReq) error
 }
 
 type taskMng interface {
-	ProcessDialogBegin(dialogId int64) (string, error)
+	ProcessDialogBegin(dialogId int64) (schema.TaskMsg, error)
+	ProcessDialogReply(dialogId int64) (schema.TaskMsg, error)
+	RequestAccess(m schema.Message) (string, error)
+	Lang(m schema.Message) i18n.Lang
 }
 
-func NewRouter(users []string, dialogMng dialogMng, taskMng taskMng) *Router {
-	return &Router{allowedUsers: users, dialogMng: dialogMng, taskMng: taskMng}
+func NewRouter(cfg Config, users users, dialogMng dialogMng, taskMng taskMng) *Router {
+	return &Router{users: users, dialogMng: dialogMng, taskMng: taskMng, limiter: newLimiter(cfg)}
 }
 
-func (r *Router) ProcessMsg(m schema.Message) schema.TaskMsg {
+// ProcessMsg answers the message and returns the id of its dialog, errors
+// for the user come in their language.
+func (r *Router) ProcessMsg(m schema.Message) (schema.TaskMsg, int64, error) {
 	m.Type = schema.MessageTypeUser
+	reply, dialogId, err := r.processMsg(m)
+	return reply, dialogId, schema.Translate(err, r.taskMng.Lang(m))
+}
+
+func (r *Router) processMsg(m schema.Message) (schema.TaskMsg, int64, error) {
 	reply := schema.TaskMsg{
 		ChatId:         m.ChatId,
 		ReplyMessageId: m.MessageId,
 	}
-	if !slices.Contains(r.allowedUsers, m.UserName) {
-		reply.Text = fmt.Sprintf("I don't answer to user %s", m.UserName)
-		return reply
+	err := r.limit(m.UserId, m.UserName)
+	if err != nil {
+		return reply, 0, err
+	}
+	role, err := r.users.Role(m.UserId, m.UserName)
+	if err != nil {
+		return reply, 0, err
+	}
+	if role == "" {
+		// only a private chat with a known user id can ask for access
+		if m.UserId == 0 || (m.ChatType != "" && m.ChatType != schema.ChatTypePrivate) {
+			return reply, 0, schema.Localized(schema.ErrCodeUnauthorizedUser, i18n.NotAnswering, userLabel(m.UserId, m.UserName))
+		}
+		reply.Text, err = r.taskMng.RequestAccess(m)
+		return reply, 0, err
 	}
 
-	dialogId, err := r.dialogMng.Create(m)
+	dialogId, isReply, err := r.dialogMng.Receive(m)
 	if err != nil {
-		reply.Text = err.Error()
-		return reply
+		return reply, 0, err
 	}
 
-	reply.Text, err = r.taskMng.ProcessDialogBegin(dialogId)
+	var answer schema.TaskMsg
+	if isReply {
+		answer, err = r.taskMng.ProcessDialogReply(dialogId)
+	} else {
+		answer, err = r.taskMng.ProcessDialogBegin(dialogId)
+	}
 	if err != nil {
-		reply.Text = err.Error()
-		return reply
+		return reply, dialogId, err
 	}
+	reply.Text = answer.Text
+	reply.Buttons = answer.Buttons
 
-	return reply
+	return reply, dialogId, nil
+}
+
+// ProcessCallback answers a pressed button with its data as a reply to the
+// bot message of the button. Data starting with / is a command of the
+// user who pressed, e.g. the approve buttons of access requests.
+func (r *Router) ProcessCallback(cb schema.CallbackReq) (schema.TaskMsg, int64, error) {
+	reply, dialogId, err := r.processCallback(cb)
+	lang := r.taskMng.Lang(schema.Message{UserId: cb.UserId, LanguageCode: cb.LanguageCode})
+	return reply, dialogId, schema.Translate(err, lang)
+}
+
+func (r *Router) processCallback(cb schema.CallbackReq) (schema.TaskMsg, int64, error) {
+	reply := schema.TaskMsg{
+		ChatId:         cb.ChatId,
+		ReplyMessageId: cb.MessageId,
+	}
+	err := r.limit(cb.UserId, cb.UserName)
+	if err != nil {
+		return reply, 0, err
+	}
+	role, err := r.users.Role(cb.UserId, cb.UserName)
+	if err != nil {
+		return reply, 0, err
+	}
+	if role == "" {
+		return reply, 0, schema.Localized(schema.ErrCodeUnauthorizedUser, i18n.NotAnswering, userLabel(cb.UserId, cb.UserName))
+	}
+
+	if strings.HasPrefix(cb.Data, "/") {
+		dialogId, _, err := r.dialogMng.Receive(schema.Message{
+			UserId:       cb.UserId,
+			UserName:     cb.UserName,
+			MessageId:    cb.MessageId,
+			ChatId:       cb.ChatId,
+			Text:         cb.Data,
+			Type:         schema.MessageTypeUser,
+			LanguageCode: cb.LanguageCode,
+		})
+		if err != nil {
+			return reply, 0, err
+		}
+		answer, err := r.taskMng.ProcessDialogBegin(dialogId)
+		if err != nil {
+			return reply, dialogId, err
+		}
+		reply.Text = answer.Text
+		reply.Buttons = answer.Buttons
+		return reply, dialogId, nil
+	}
+
+	dialogId, err := r.dialogMng.Answer(schema.Message{
+		UserId:           cb.UserId,
+		UserName:         cb.UserName,
+		MessageId:        cb.MessageId,
+		ReplyToMessageID: cb.MessageId,
+		ChatId:           cb.ChatId,
+		Text:             cb.Data,
+		Type:             schema.MessageTypeUser,
+		LanguageCode:     cb.LanguageCode,
+	})
+	if err != nil {
+		return reply, 0, err
+	}
+
+	answer, err := r.taskMng.ProcessDialogReply(dialogId)
+	if err != nil {
+		return reply, dialogId, err
+	}
+	reply.Text = answer.Text
+	reply.Buttons = answer.Buttons
+
+	return reply, dialogId, nil
+}
+
+// limit takes a token of the user, strangers are limited too, so they
+// can't flood access requests.
+func (r *Router) limit(userId int64, userName string) error {
+	wait, ok := r.limiter.allow(userLabel(userId, userName))
+	if !ok {
+		return schema.Localized(schema.ErrCodeRateLimited, i18n.RateLimited, wait.String())
+	}
+	return nil
+}
+
+// ProcessBotMsg links a message sent by the bot to its dialog.
+func (r *Router) ProcessBotMsg(req schema.BotMsgReq) error {
+	return r.dialogMng.AddBotMessage(req)
+}
+
+// userLabel names the user in replies, the id tells the admin what to
+// put into the config.
+func userLabel(userId int64, userName string) string {
+	if userId == 0 {
+		return userName
+	}
+	if userName == "" {
+		return fmt.Sprintf("%d", userId)
+	}
+	return fmt.Sprintf("%s (%d)", userName, userId)
 }
@@ -0,0 +1,696 @@
+// Package memstore keeps mcore data in memory. It needs no cgo and no files,
+// which makes it the backend for tests and local runs.
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// MemStore guards data with a mutex, a transaction holds it until the end
+// and restores a copy of data on rollback.
+type MemStore struct {
+	mu sync.Mutex
+	d  *data
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		d: &data{
+			tasks:    map[int64]schema.Task{},
+			dialogs:  map[int64]schema.Dialog{},
+			alerts:   map[alertKey]schema.TaskAlert{},
+			refs:     map[refKey]int64{},
+			users:    map[int64]schema.User{},
+			settings: map[int64]schema.Settings{},
+			aliases:  map[int64]schema.Aliases{},
+		},
+	}
+}
+
+func (s *MemStore) InTx(fn func(tx storage.Repo) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.d.clone()
+	err := fn(s.d)
+	if err != nil {
+		s.d = snapshot
+		return err
+	}
+	return nil
+}
+
+func (s *MemStore) AddTask(task schema.Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddTask(task)
+}
+
+func (s *MemStore) ClaimTask(t schema.TaskType) (schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ClaimTask(t)
+}
+
+func (s *MemStore) GetTaskById(id int64) (schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.GetTaskById(id)
+}
+
+func (s *MemStore) ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListTasksByStatus(status)
+}
+
+func (s *MemStore) ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListUserTasks(userId, status)
+}
+
+func (s *MemStore) CountOpenTasks(taskType schema.TaskType, userId int64) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.CountOpenTasks(taskType, userId)
+}
+
+func (s *MemStore) ListDialogTasks(dialogId int64) ([]schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListDialogTasks(dialogId)
+}
+
+func (s *MemStore) UpdateTaskStatus(task schema.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.UpdateTaskStatus(task)
+}
+
+func (s *MemStore) AddDialog(d schema.Dialog) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddDialog(d)
+}
+
+func (s *MemStore) GetDialogById(id int64) (schema.Dialog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.GetDialogById(id)
+}
+
+func (s *MemStore) UpdateDialog(d schema.Dialog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.UpdateDialog(d)
+}
+
+func (s *MemStore) ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListUserDialogs(userId, limit)
+}
+
+func (s *MemStore) ListTasks(afterId int64, limit int) ([]schema.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListTasks(afterId, limit)
+}
+
+func (s *MemStore) ListDialogs(afterId int64, limit int) ([]schema.Dialog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListDialogs(afterId, limit)
+}
+
+func (s *MemStore) RestoreTask(task schema.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.RestoreTask(task)
+}
+
+func (s *MemStore) RestoreDialog(d schema.Dialog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.RestoreDialog(d)
+}
+
+func (s *MemStore) AddTaskEvent(e schema.TaskEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddTaskEvent(e)
+}
+
+func (s *MemStore) ListTaskEvents(since time.Time) ([]schema.TaskEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListTaskEvents(since)
+}
+
+func (s *MemStore) AddTaskAlert(a schema.TaskAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddTaskAlert(a)
+}
+
+func (s *MemStore) ListTaskAlerts() ([]schema.TaskAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListTaskAlerts()
+}
+
+func (s *MemStore) DeleteTaskAlert(taskId int64, status schema.TaskStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.DeleteTaskAlert(taskId, status)
+}
+
+func (s *MemStore) AddDialogRef(chatId int64, messageId int, dialogId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddDialogRef(chatId, messageId, dialogId)
+}
+
+func (s *MemStore) GetDialogIdByRef(chatId int64, messageId int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.GetDialogIdByRef(chatId, messageId)
+}
+
+func (s *MemStore) AddUser(u schema.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.AddUser(u)
+}
+
+func (s *MemStore) GetUser(id int64) (schema.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.GetUser(id)
+}
+
+func (s *MemStore) UpdateUser(u schema.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.UpdateUser(u)
+}
+
+func (s *MemStore) ListUsers() ([]schema.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListUsers()
+}
+
+func (s *MemStore) SetUserSetting(userId int64, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.SetUserSetting(userId, key, value)
+}
+
+func (s *MemStore) ListUserSettings(userId int64) (schema.Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListUserSettings(userId)
+}
+
+func (s *MemStore) ListAllUserSettings() ([]schema.UserSetting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListAllUserSettings()
+}
+
+func (s *MemStore) SetUserAlias(userId int64, name, expansion string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.SetUserAlias(userId, name, expansion)
+}
+
+func (s *MemStore) ListUserAliases(userId int64) (schema.Aliases, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListUserAliases(userId)
+}
+
+func (s *MemStore) ListAllUserAliases() ([]schema.UserAlias, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.ListAllUserAliases()
+}
+
+func (s *MemStore) DeleteAllTasks() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.DeleteAllTasks()
+}
+
+func (s *MemStore) DeleteAllDialogs() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d.DeleteAllDialogs()
+}
+
+func (s *MemStore) Ready(_ context.Context) error {
+	return nil
+}
+
+func (s *MemStore) DbClose() {}
+
+// data implements storage.Repo without locking.
+type data struct {
+	tasks        map[int64]schema.Task
+	dialogs      map[int64]schema.Dialog
+	events       []schema.TaskEvent
+	alerts       map[alertKey]schema.TaskAlert
+	refs         map[refKey]int64
+	users        map[int64]schema.User
+	settings     map[int64]schema.Settings
+	aliases      map[int64]schema.Aliases
+	lastTaskId   int64
+	lastDialogId int64
+	lastEventId  int64
+}
+
+func (d *data) clone() *data {
+	c := *d
+	c.tasks = maps.Clone(d.tasks)
+	c.dialogs = maps.Clone(d.dialogs)
+	c.events = slices.Clone(d.events)
+	c.alerts = maps.Clone(d.alerts)
+	c.refs = maps.Clone(d.refs)
+	c.users = maps.Clone(d.users)
+	c.settings = map[int64]schema.Settings{}
+	for id, settings := range d.settings {
+		c.settings[id] = maps.Clone(settings)
+	}
+	c.aliases = map[int64]schema.Aliases{}
+	for id, aliases := range d.aliases {
+		c.aliases[id] = maps.Clone(aliases)
+	}
+	return &c
+}
+
+func (d *data) AddTask(task schema.Task) (int64, error) {
+	if task.Type == schema.TaskTypeUndefined {
+		return 0, errors.New("invalid task type")
+	}
+
+	d.lastTaskId++
+	now := time.Now()
+	task.Id = d.lastTaskId
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	d.tasks[task.Id] = task
+	return task.Id, nil
+}
+
+func (d *data) ClaimTask(t schema.TaskType) (schema.Task, error) {
+	if t == schema.TaskTypeUndefined {
+		return schema.Task{}, fmt.Errorf("claimTask: wrong task type")
+	}
+
+	var found schema.Task
+	for _, task := range d.tasks {
+		if task.Type != t || task.Status != schema.TaskStatusCreate {
+			continue
+		}
+		if found.Id == 0 || task.Id < found.Id {
+			found = task
+		}
+	}
+	if found.Id == 0 {
+		return schema.Task{}, nil
+	}
+
+	found.Status = schema.TaskStatusSended
+	found.UpdatedAt = time.Now()
+	d.tasks[found.Id] = found
+	return found, nil
+}
+
+func (d *data) GetTaskById(id int64) (schema.Task, error) {
+	return d.tasks[id], nil
+}
+
+func (d *data) ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error) {
+	var ret []schema.Task
+	for _, id := range sortedIds(d.tasks, 0, len(d.tasks)) {
+		if d.tasks[id].Status == status {
+			ret = append(ret, d.tasks[id])
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error) {
+	var ret []schema.Task
+	for _, id := range sortedIds(d.tasks, 0, len(d.tasks)) {
+		task := d.tasks[id]
+		dialog := d.dialogs[task.DialogId]
+		if task.Status == status && task.DialogId != 0 && dialog.UserId() == userId {
+			ret = append(ret, task)
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) CountOpenTasks(taskType schema.TaskType, userId int64) (int, int, error) {
+	var all, mine int
+	for _, task := range d.tasks {
+		if task.Type != taskType || (task.Status != schema.TaskStatusCreate && task.Status != schema.TaskStatusSended) {
+			continue
+		}
+		all++
+		dialog := d.dialogs[task.DialogId]
+		if task.DialogId != 0 && dialog.UserId() == userId {
+			mine++
+		}
+	}
+	return all, mine, nil
+}
+
+func (d *data) ListDialogTasks(dialogId int64) ([]schema.Task, error) {
+	var ret []schema.Task
+	for _, id := range sortedIds(d.tasks, 0, len(d.tasks)) {
+		if d.tasks[id].DialogId == dialogId {
+			ret = append(ret, d.tasks[id])
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) UpdateTaskStatus(task schema.Task) error {
+	if task.Id == 0 {
+		return fmt.Errorf("smt is wrong try to updata task without id")
+	}
+
+	saved, ok := d.tasks[task.Id]
+	if !ok {
+		return nil
+	}
+	saved.Status = task.Status
+	saved.UpdatedAt = time.Now()
+	d.tasks[task.Id] = saved
+	return nil
+}
+
+func (d *data) AddDialog(dialog schema.Dialog) (int64, error) {
+	d.lastDialogId++
+	now := time.Now()
+	dialog.Id = d.lastDialogId
+	dialog = cloneDialog(dialog)
+	dialog.CreatedAt = now
+	dialog.UpdatedAt = now
+	d.dialogs[dialog.Id] = dialog
+	return dialog.Id, nil
+}
+
+func (d *data) GetDialogById(id int64) (schema.Dialog, error) {
+	dialog, ok := d.dialogs[id]
+	if !ok {
+		return schema.Dialog{}, fmt.Errorf("getDialogById = %d not found", id)
+	}
+	return cloneDialog(dialog), nil
+}
+
+func (d *data) UpdateDialog(dialog schema.Dialog) error {
+	if dialog.Id == 0 {
+		return fmt.Errorf("updateDialog dialog.id is 0 nothink to update")
+	}
+
+	saved, ok := d.dialogs[dialog.Id]
+	if !ok {
+		return nil
+	}
+	saved.DialogStatus = dialog.DialogStatus
+	saved.Messages = slices.Clone(dialog.Messages)
+	saved.State = cloneState(dialog.State)
+	saved.UpdatedAt = time.Now()
+	d.dialogs[dialog.Id] = saved
+	return nil
+}
+
+func (d *data) ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error) {
+	var ret []schema.Dialog
+	ids := sortedIds(d.dialogs, 0, len(d.dialogs))
+	for i := len(ids) - 1; i >= 0 && len(ret) < limit; i-- {
+		dialog := d.dialogs[ids[i]]
+		if dialog.UserId() == userId {
+			ret = append(ret, cloneDialog(dialog))
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) ListTasks(afterId int64, limit int) ([]schema.Task, error) {
+	var ret []schema.Task
+	for _, id := range sortedIds(d.tasks, afterId, limit) {
+		ret = append(ret, d.tasks[id])
+	}
+	return ret, nil
+}
+
+func (d *data) ListDialogs(afterId int64, limit int) ([]schema.Dialog, error) {
+	var ret []schema.Dialog
+	for _, id := range sortedIds(d.dialogs, afterId, limit) {
+		ret = append(ret, cloneDialog(d.dialogs[id]))
+	}
+	return ret, nil
+}
+
+// cloneDialog copies the parts of a dialog that share memory,
+// so callers never change the stored one.
+func cloneDialog(dialog schema.Dialog) schema.Dialog {
+	dialog.Messages = slices.Clone(dialog.Messages)
+	dialog.State = cloneState(dialog.State)
+	return dialog
+}
+
+func cloneState(st schema.DialogState) schema.DialogState {
+	st.Path = slices.Clone(st.Path)
+	st.Args = maps.Clone(st.Args)
+	st.Buttons = slices.Clone(st.Buttons)
+	return st
+}
+
+// sortedIds returns up to limit ids of m greater than afterId in order.
+func sortedIds[V any](m map[int64]V, afterId int64, limit int) []int64 {
+	var ids []int64
+	for id := range m {
+		if id > afterId {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+func (d *data) RestoreTask(task schema.Task) error {
+	if _, ok := d.tasks[task.Id]; ok {
+		return fmt.Errorf("restoreTask %d already exists", task.Id)
+	}
+	d.tasks[task.Id] = task
+	d.lastTaskId = max(d.lastTaskId, task.Id)
+	return nil
+}
+
+func (d *data) RestoreDialog(dialog schema.Dialog) error {
+	if _, ok := d.dialogs[dialog.Id]; ok {
+		return fmt.Errorf("restoreDialog %d already exists", dialog.Id)
+	}
+	d.dialogs[dialog.Id] = cloneDialog(dialog)
+	d.lastDialogId = max(d.lastDialogId, dialog.Id)
+	return nil
+}
+
+func (d *data) AddTaskEvent(e schema.TaskEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	d.lastEventId++
+	e.Id = d.lastEventId
+	d.events = append(d.events, e)
+	return nil
+}
+
+func (d *data) ListTaskEvents(since time.Time) ([]schema.TaskEvent, error) {
+	var ret []schema.TaskEvent
+	for _, e := range d.events {
+		if !e.CreatedAt.Before(since) {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
+
+type alertKey struct {
+	taskId int64
+	status schema.TaskStatus
+}
+
+func (d *data) AddTaskAlert(a schema.TaskAlert) error {
+	key := alertKey{taskId: a.TaskId, status: a.Status}
+	if _, ok := d.alerts[key]; ok {
+		return fmt.Errorf("addTaskAlert %d already exists", a.TaskId)
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	d.alerts[key] = a
+	return nil
+}
+
+func (d *data) ListTaskAlerts() ([]schema.TaskAlert, error) {
+	ret := slices.Collect(maps.Values(d.alerts))
+	slices.SortFunc(ret, func(a, b schema.TaskAlert) int {
+		return cmp.Compare(a.TaskId, b.TaskId)
+	})
+	return ret, nil
+}
+
+func (d *data) DeleteTaskAlert(taskId int64, status schema.TaskStatus) error {
+	delete(d.alerts, alertKey{taskId: taskId, status: status})
+	return nil
+}
+
+type refKey struct {
+	chatId    int64
+	messageId int
+}
+
+func (d *data) AddDialogRef(chatId int64, messageId int, dialogId int64) error {
+	d.refs[refKey{chatId: chatId, messageId: messageId}] = dialogId
+	return nil
+}
+
+func (d *data) GetDialogIdByRef(chatId int64, messageId int) (int64, error) {
+	return d.refs[refKey{chatId: chatId, messageId: messageId}], nil
+}
+
+func (d *data) AddUser(u schema.User) error {
+	if _, ok := d.users[u.Id]; ok {
+		return fmt.Errorf("addUser %d already exists", u.Id)
+	}
+	now := time.Now()
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = now
+	}
+	if u.UpdatedAt.IsZero() {
+		u.UpdatedAt = now
+	}
+	d.users[u.Id] = u
+	return nil
+}
+
+func (d *data) GetUser(id int64) (schema.User, error) {
+	return d.users[id], nil
+}
+
+func (d *data) UpdateUser(u schema.User) error {
+	if u.Id == 0 {
+		return fmt.Errorf("updateUser user.id is 0 nothink to update")
+	}
+
+	saved, ok := d.users[u.Id]
+	if !ok {
+		return nil
+	}
+	u.CreatedAt = saved.CreatedAt
+	u.UpdatedAt = time.Now()
+	d.users[u.Id] = u
+	return nil
+}
+
+func (d *data) ListUsers() ([]schema.User, error) {
+	var ret []schema.User
+	for _, id := range sortedIds(d.users, 0, len(d.users)) {
+		ret = append(ret, d.users[id])
+	}
+	return ret, nil
+}
+
+func (d *data) SetUserSetting(userId int64, key, value string) error {
+	if value == "" {
+		delete(d.settings[userId], key)
+		return nil
+	}
+	if d.settings[userId] == nil {
+		d.settings[userId] = schema.Settings{}
+	}
+	d.settings[userId][key] = value
+	return nil
+}
+
+func (d *data) ListUserSettings(userId int64) (schema.Settings, error) {
+	settings := maps.Clone(d.settings[userId])
+	if settings == nil {
+		settings = schema.Settings{}
+	}
+	return settings, nil
+}
+
+func (d *data) ListAllUserSettings() ([]schema.UserSetting, error) {
+	var ret []schema.UserSetting
+	for _, id := range sortedIds(d.settings, 0, len(d.settings)) {
+		for _, key := range slices.Sorted(maps.Keys(d.settings[id])) {
+			ret = append(ret, schema.UserSetting{UserId: id, Key: key, Value: d.settings[id][key]})
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) SetUserAlias(userId int64, name, expansion string) error {
+	if expansion == "" {
+		delete(d.aliases[userId], name)
+		return nil
+	}
+	if d.aliases[userId] == nil {
+		d.aliases[userId] = schema.Aliases{}
+	}
+	d.aliases[userId][name] = expansion
+	return nil
+}
+
+func (d *data) ListUserAliases(userId int64) (schema.Aliases, error) {
+	aliases := maps.Clone(d.aliases[userId])
+	if aliases == nil {
+		aliases = schema.Aliases{}
+	}
+	return aliases, nil
+}
+
+func (d *data) ListAllUserAliases() ([]schema.UserAlias, error) {
+	var ret []schema.UserAlias
+	for _, id := range sortedIds(d.aliases, 0, len(d.aliases)) {
+		for _, name := range slices.Sorted(maps.Keys(d.aliases[id])) {
+			ret = append(ret, schema.UserAlias{UserId: id, Name: name, Expansion: d.aliases[id][name]})
+		}
+	}
+	return ret, nil
+}
+
+func (d *data) DeleteAllTasks() error {
+	d.tasks = map[int64]schema.Task{}
+	d.events = nil
+	d.alerts = map[alertKey]schema.TaskAlert{}
+	return nil
+}
+
+func (d *data) DeleteAllDialogs() error {
+	d.dialogs = map[int64]schema.Dialog{}
+	d.refs = map[refKey]int64{}
+	return nil
+}
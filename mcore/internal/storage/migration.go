@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Query   string
+}
+
+// LoadMigrations reads files named <version>_<name>.sql from dir
+// ordered by version.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("loadMigrations read dir: %w", err)
+	}
+
+	var ret []Migration
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".sql")
+		prefix, _, found := strings.Cut(name, "_")
+		if !found {
+			return nil, fmt.Errorf("loadMigrations wrong file name %s", e.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations wrong version %s: %w", e.Name(), err)
+		}
+		query, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("loadMigrations read %s: %w", e.Name(), err)
+		}
+		ret = append(ret, Migration{Version: version, Name: name, Query: string(query)})
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("loadMigrations no migrations in %s", dir)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Version < ret[j].Version })
+	for i := 1; i < len(ret); i++ {
+		if ret[i].Version == ret[i-1].Version {
+			return nil, fmt.Errorf("loadMigrations duplicate version %d", ret[i].Version)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,49 @@
+package msqlclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *SqliteClient) AddTaskAlert(a schema.TaskAlert) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	sqlQuery := "INSERT INTO task_alert( task_id, status, created_at) VALUES( ?, ?, ?);"
+	_, err := c.q.Exec(sqlQuery, a.TaskId, a.Status, a.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("addTaskAlert %d: %w", a.TaskId, err)
+	}
+	return nil
+}
+
+func (c *SqliteClient) ListTaskAlerts() ([]schema.TaskAlert, error) {
+	rows, err := c.q.Query("SELECT task_id, status, created_at FROM task_alert ORDER BY task_id")
+	if err != nil {
+		return nil, fmt.Errorf("listTaskAlerts: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.TaskAlert
+	for rows.Next() {
+		var a schema.TaskAlert
+		var createdAt int64
+		err = rows.Scan(&a.TaskId, &a.Status, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("listTaskAlerts scan: %w", err)
+		}
+		a.CreatedAt = time.UnixMilli(createdAt)
+		ret = append(ret, a)
+	}
+	return ret, rows.Err()
+}
+
+func (c *SqliteClient) DeleteTaskAlert(taskId int64, status schema.TaskStatus) error {
+	_, err := c.q.Exec("DELETE FROM task_alert WHERE task_id = ? AND status = ?;", taskId, status)
+	if err != nil {
+		return fmt.Errorf("deleteTaskAlert %d: %w", taskId, err)
+	}
+	return nil
+}
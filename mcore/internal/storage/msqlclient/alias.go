@@ -0,0 +1,63 @@
+package msqlclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// SetUserAlias saves the expansion of the alias, an empty one deletes it.
+func (c *SqliteClient) SetUserAlias(userId int64, name, expansion string) error {
+	if expansion == "" {
+		_, err := c.q.Exec("DELETE FROM user_alias WHERE user_id = ? AND name = ?", userId, name)
+		if err != nil {
+			return fmt.Errorf("setUserAlias delete %s of %d: %w", name, userId, err)
+		}
+		return nil
+	}
+	sqlQuery := "INSERT OR REPLACE INTO user_alias( user_id, name, expansion, updated_at) VALUES( ?, ?, ?, ?);"
+	_, err := c.q.Exec(sqlQuery, userId, name, expansion, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("setUserAlias %s of %d: %w", name, userId, err)
+	}
+	return nil
+}
+
+func (c *SqliteClient) ListUserAliases(userId int64) (schema.Aliases, error) {
+	rows, err := c.q.Query("SELECT name, expansion FROM user_alias WHERE user_id = ?", userId)
+	if err != nil {
+		return nil, fmt.Errorf("listUserAliases %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	aliases := schema.Aliases{}
+	for rows.Next() {
+		var name, expansion string
+		err = rows.Scan(&name, &expansion)
+		if err != nil {
+			return nil, fmt.Errorf("listUserAliases scan: %w", err)
+		}
+		aliases[name] = expansion
+	}
+	return aliases, rows.Err()
+}
+
+func (c *SqliteClient) ListAllUserAliases() ([]schema.UserAlias, error) {
+	rows, err := c.q.Query("SELECT user_id, name, expansion FROM user_alias ORDER BY user_id, name")
+	if err != nil {
+		return nil, fmt.Errorf("listAllUserAliases: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.UserAlias
+	for rows.Next() {
+		var a schema.UserAlias
+		err = rows.Scan(&a.UserId, &a.Name, &a.Expansion)
+		if err != nil {
+			return nil, fmt.Errorf("listAllUserAliases scan: %w", err)
+		}
+		ret = append(ret, a)
+	}
+	return ret, rows.Err()
+}
@@ -0,0 +1,71 @@
+package msqlclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup copies the live db into a new file at destPath with the sqlite
+// online backup api. Writers are not blocked, the copy is consistent.
+func (c *SqliteClient) Backup(ctx context.Context, destPath string) error {
+	_, err := os.Stat(destPath)
+	if err == nil {
+		return fmt.Errorf("backup file %s already exists", destPath)
+	}
+	err = os.MkdirAll(filepath.Dir(destPath), 0755)
+	if err != nil {
+		return fmt.Errorf("backup create dir: %w", err)
+	}
+
+	destDb, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("backup open dest: %w", err)
+	}
+	defer destDb.Close()
+
+	destConn, err := destDb.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("backup dest conn: %w", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("backup src conn: %w", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			dest, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup dest is not a sqlite conn")
+			}
+			src, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("backup src is not a sqlite conn")
+			}
+
+			b, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return fmt.Errorf("backup init: %w", err)
+			}
+			// -1 copies all pages in one step
+			_, err = b.Step(-1)
+			if err != nil {
+				_ = b.Finish()
+				return fmt.Errorf("backup step: %w", err)
+			}
+			err = b.Finish()
+			if err != nil {
+				return fmt.Errorf("backup finish: %w", err)
+			}
+			return nil
+		})
+	})
+}
@@ -1,13 +1,25 @@
 package msqlclient
 
 func (c *SqliteClient) DeleteAllTasks() error {
+	_, err := c.q.Exec("delete from task_event;")
+	if err != nil {
+		return err
+	}
+	_, err = c.q.Exec("delete from task_alert;")
+	if err != nil {
+		return err
+	}
 	sqlQuery := "delete from task;"
-	_, err := c.db.Exec(sqlQuery)
+	_, err = c.q.Exec(sqlQuery)
 	return err
 }
 
 func (c *SqliteClient) DeleteAllDialogs() error {
+	_, err := c.q.Exec("delete from dialog_ref;")
+	if err != nil {
+		return err
+	}
 	sqlQuery := "delete from dialog;"
-	_, err := c.db.Exec(sqlQuery)
+	_, err = c.q.Exec(sqlQuery)
 	return err
 }
@@ -3,25 +3,24 @@ package msqlclient
 import (
 	"fmt"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
+	"time"
 )
 
-const createDialog string = `
-CREATE TABLE IF NOT EXISTS dialog (
-    id INTEGER NOT NULL PRIMARY KEY,
-	key TEXT NOT NULL,
-	dialogstatus INTEGER NOT NULL,
-	data blob null default (x'') 
-  );
-`
+const getDialogByIdQuery = "SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE id = ?"
 
 func (c *SqliteClient) AddDialog(d schema.Dialog) (int64, error) {
 	data, err := d.GetMessagesAsByte()
 	if err != nil {
 		return 0, fmt.Errorf("addDialog can't parse messages: %w", err)
 	}
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return 0, fmt.Errorf("addDialog can't marshal state: %w", err)
+	}
 
-	sqlQuery := `INSERT INTO dialog( key, dialogstatus, data) VALUES( ?, ?, ?);`
-	res, err := c.db.Exec(sqlQuery, d.Key, d.DialogStatus, data)
+	now := time.Now().UnixMilli()
+	sqlQuery := `INSERT INTO dialog( key, dialogstatus, data, state, user_id, created_at, updated_at) VALUES( ?, ?, ?, ?, ?, ?, ?);`
+	res, err := c.q.Exec(sqlQuery, d.Key, d.DialogStatus, data, state, d.UserId(), now, now)
 	if err != nil {
 		return 0, fmt.Errorf("insert addDialog: %w", err)
 	}
@@ -34,20 +33,11 @@ func (c *SqliteClient) AddDialog(d schema.Dialog) (int64, error) {
 }
 
 func (c *SqliteClient) GetDialogById(id int64) (schema.Dialog, error) {
-	sqlQuery := "SELECT id, key, dialogstatus, data FROM dialog WHERE id = ?"
-
-	row := c.db.QueryRow(sqlQuery, id)
-	d := &schema.Dialog{}
-	var data []byte
-	err := row.Scan(&d.Id, &d.Key, &d.DialogStatus, &data)
-	if err != nil {
-		return *d, fmt.Errorf("getDialogById = %d scan %w", d.Id, err)
-	}
-	err = d.SetMessagesFromByte(data)
+	d, err := scanDialog(c.stmt(c.stmts.getDialogById).QueryRow(id))
 	if err != nil {
-		return *d, fmt.Errorf("getDialogById unmarshal %w", err)
+		return d, fmt.Errorf("getDialogById = %d %w", id, err)
 	}
-	return *d, err
+	return d, nil
 }
 
 func (c *SqliteClient) UpdateDialog(d schema.Dialog) error {
@@ -55,14 +45,61 @@ func (c *SqliteClient) UpdateDialog(d schema.Dialog) error {
 		return fmt.Errorf("updateDialog dialog.id is 0 nothink to update")
 	}
 
-	sqlQuery := "UPDATE dialog SET dialogstatus = ?, data = ? WHERE id = ?"
+	sqlQuery := "UPDATE dialog SET dialogstatus = ?, data = ?, state = ?, updated_at = ? WHERE id = ?"
 	msgByte, err := d.GetMessagesAsByte()
 	if err != nil {
 		return fmt.Errorf("updateDialog cat't marshal messages %w", err)
 	}
-	_, err = c.db.Exec(sqlQuery, d.DialogStatus, msgByte, d.Id)
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return fmt.Errorf("updateDialog can't marshal state %w", err)
+	}
+	_, err = c.q.Exec(sqlQuery, d.DialogStatus, msgByte, state, time.Now().UnixMilli(), d.Id)
 	if err != nil {
 		return fmt.Errorf("updateDialog : %w", err)
 	}
 	return nil
 }
+
+func (c *SqliteClient) ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error) {
+	sqlQuery := `
+SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE user_id = ? ORDER BY id DESC LIMIT ?
+`
+	rows, err := c.q.Query(sqlQuery, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listUserDialogs: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Dialog
+	for rows.Next() {
+		d, err := scanDialog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listUserDialogs %w", err)
+		}
+		ret = append(ret, d)
+	}
+	return ret, rows.Err()
+}
+
+func scanDialog(row scanner) (schema.Dialog, error) {
+	d := schema.Dialog{}
+	var data, state []byte
+	var createdAt, updatedAt int64
+
+	err := row.Scan(&d.Id, &d.Key, &d.DialogStatus, &data, &state, &createdAt, &updatedAt)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("scan dialog: %w", err)
+	}
+	d.CreatedAt = time.UnixMilli(createdAt)
+	d.UpdatedAt = time.UnixMilli(updatedAt)
+	err = d.SetMessagesFromByte(data)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("unmarshal dialog %d: %w", d.Id, err)
+	}
+	err = d.SetStateFromByte(state)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("unmarshal dialog state %d: %w", d.Id, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,29 @@
+package msqlclient
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (c *SqliteClient) AddDialogRef(chatId int64, messageId int, dialogId int64) error {
+	sqlQuery := "INSERT OR REPLACE INTO dialog_ref( chat_id, message_id, dialog_id) VALUES( ?, ?, ?);"
+	_, err := c.q.Exec(sqlQuery, chatId, messageId, dialogId)
+	if err != nil {
+		return fmt.Errorf("addDialogRef %d: %w", dialogId, err)
+	}
+	return nil
+}
+
+// GetDialogIdByRef returns 0 when the message belongs to no dialog.
+func (c *SqliteClient) GetDialogIdByRef(chatId int64, messageId int) (int64, error) {
+	var dialogId int64
+	err := c.q.QueryRow("SELECT dialog_id FROM dialog_ref WHERE chat_id = ? AND message_id = ?", chatId, messageId).Scan(&dialogId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getDialogIdByRef %d/%d: %w", chatId, messageId, err)
+	}
+	return dialogId, nil
+}
@@ -0,0 +1,85 @@
+package msqlclient
+
+import (
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *SqliteClient) ListTasks(afterId int64, limit int) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE id > ? ORDER BY id LIMIT ?
+`
+	rows, err := c.q.Query(sqlQuery, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listTasks %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
+}
+
+func (c *SqliteClient) ListDialogs(afterId int64, limit int) ([]schema.Dialog, error) {
+	sqlQuery := `
+SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE id > ? ORDER BY id LIMIT ?
+`
+	rows, err := c.q.Query(sqlQuery, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listDialogs: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Dialog
+	for rows.Next() {
+		d, err := scanDialog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listDialogs %w", err)
+		}
+		ret = append(ret, d)
+	}
+	return ret, rows.Err()
+}
+
+func (c *SqliteClient) RestoreTask(task schema.Task) error {
+	data, err := task.TaskData.Marshal()
+	if err != nil {
+		return fmt.Errorf("restoreTask task data marshal: %w", err)
+	}
+	sqlQuery := `
+INSERT INTO task( id, dialog, status, type, data, created_at, updated_at)
+	VALUES( ?, ?, ?, ?, ?, ?, ?);
+`
+	_, err = c.q.Exec(sqlQuery, task.Id, task.DialogId, task.Status, task.Type, data,
+		task.CreatedAt.UnixMilli(), task.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("restoreTask %d: %w", task.Id, err)
+	}
+	return nil
+}
+
+func (c *SqliteClient) RestoreDialog(d schema.Dialog) error {
+	data, err := d.GetMessagesAsByte()
+	if err != nil {
+		return fmt.Errorf("restoreDialog can't parse messages: %w", err)
+	}
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return fmt.Errorf("restoreDialog can't marshal state: %w", err)
+	}
+	sqlQuery := `
+INSERT INTO dialog( id, key, dialogstatus, data, state, user_id, created_at, updated_at) VALUES( ?, ?, ?, ?, ?, ?, ?, ?);
+`
+	_, err = c.q.Exec(sqlQuery, d.Id, d.Key, d.DialogStatus, data, state, d.UserId(), d.CreatedAt.UnixMilli(), d.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("restoreDialog %d: %w", d.Id, err)
+	}
+	return nil
+}
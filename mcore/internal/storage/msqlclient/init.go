@@ -1,40 +1,23 @@
 package msqlclient
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
-	"path"
+	"time"
 )
 
-func initDBIfNeeded(dirPath, fileName string) {
-	dbPath := path.Join(dirPath, fileName)
-	_, err := os.Stat(dbPath)
-	if err == nil {
-		return
-	}
-
-	err = os.MkdirAll(dirPath, 0755)
+func initDBIfNeeded(dirPath string, db *sql.DB) {
+	err := os.MkdirAll(dirPath, 0755)
 	if err != nil {
 		log.Fatalf("cant create path %s", err.Error())
 	}
-	_, err = os.Create(dbPath)
-	if err != nil {
-		log.Fatalf("cant create file %s", err.Error())
-	}
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		log.Fatalf("no open db %s", err.Error())
-	}
-	defer db.Close()
-
-	_, err = db.Exec(creatTask)
-	if err != nil {
-		log.Fatalf("create table creatTask %s", err.Error())
-	}
 
-	_, err = db.Exec(createDialog)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = migrate(ctx, db)
 	if err != nil {
-		log.Fatalf("create table createDialog %s", err.Error())
+		log.Fatalf("migrate db %s", err.Error())
 	}
 }
@@ -0,0 +1,97 @@
+package msqlclient
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at INTEGER NOT NULL
+  );
+`
+
+// migrate applies pending migrations. It holds the sqlite write lock for the
+// whole run, so a second mcore started on the same file waits for the first.
+func migrate(ctx context.Context, db *sql.DB) error {
+	migrations, err := storage.LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate get conn: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	if err != nil {
+		return fmt.Errorf("migrate lock db: %w", err)
+	}
+
+	err = applyMigrations(ctx, conn, migrations)
+	if err != nil {
+		_, _ = conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return fmt.Errorf("migrate commit: %w", err)
+	}
+	return nil
+}
+
+func applyMigrations(ctx context.Context, conn *sql.Conn, migrations []storage.Migration) error {
+	_, err := conn.ExecContext(ctx, createSchemaMigrations)
+	if err != nil {
+		return fmt.Errorf("migrate create schema_migrations: %w", err)
+	}
+
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		_, err = conn.ExecContext(ctx, m.Query)
+		if err != nil {
+			return fmt.Errorf("migrate apply %s: %w", m.Name, err)
+		}
+		_, err = conn.ExecContext(ctx, "INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?)",
+			m.Version, m.Name, time.Now().UnixMilli())
+		if err != nil {
+			return fmt.Errorf("migrate save %s: %w", m.Name, err)
+		}
+		log.Printf("migration applied: %s", m.Name)
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func schemaVersion(ctx context.Context, q queryRower) (int, error) {
+	var version int
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("schema version: %w", err)
+	}
+	return version, nil
+}
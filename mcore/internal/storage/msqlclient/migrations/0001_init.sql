@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS task (
+	id integer NOT NULL PRIMARY KEY,
+ 	dialog INTEGER NOT NULL,
+  	status INTEGER NOT NULL,
+  	type INTEGER NOT NULL,
+  	data blob null default (x'')
+  );
+
+CREATE TABLE IF NOT EXISTS dialog (
+    id INTEGER NOT NULL PRIMARY KEY,
+	key TEXT NOT NULL,
+	dialogstatus INTEGER NOT NULL,
+	data blob null default (x'')
+  );
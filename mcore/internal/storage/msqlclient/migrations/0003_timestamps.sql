@@ -0,0 +1,10 @@
+-- unix milliseconds, rows created before this migration get its time
+ALTER TABLE task ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE task SET created_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000,
+	updated_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000;
+
+ALTER TABLE dialog ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE dialog ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+UPDATE dialog SET created_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000,
+	updated_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000;
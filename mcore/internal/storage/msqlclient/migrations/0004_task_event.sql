@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_event (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	task_type INTEGER NOT NULL,
+	command TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL,
+	worker TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_event_created_idx ON task_event(created_at);
+CREATE INDEX IF NOT EXISTS task_event_task_idx ON task_event(task_id);
@@ -0,0 +1,9 @@
+ALTER TABLE dialog ADD COLUMN state BLOB NOT NULL DEFAULT (x'');
+
+-- bot messages of a dialog by telegram chat and message id, a reply to one continues the dialog
+CREATE TABLE IF NOT EXISTS dialog_ref (
+	chat_id INTEGER NOT NULL,
+	message_id INTEGER NOT NULL,
+	dialog_id INTEGER NOT NULL,
+	PRIMARY KEY (chat_id, message_id)
+);
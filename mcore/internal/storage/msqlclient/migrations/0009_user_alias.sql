@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_alias (
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	expansion TEXT NOT NULL,
+	updated_at INTEGER NOT NULL,
+	PRIMARY KEY (user_id, name)
+);
@@ -0,0 +1,4 @@
+-- the user who began the dialog, /status and /history list dialogs by it
+ALTER TABLE dialog ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+UPDATE dialog SET user_id = coalesce(json_extract(CAST(data AS TEXT), '$[0].userId'), 0) WHERE json_valid(CAST(data AS TEXT));
+CREATE INDEX IF NOT EXISTS dialog_user_idx ON dialog (user_id, id);
@@ -0,0 +1,63 @@
+package msqlclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// SetUserSetting saves the value of the setting, an empty value deletes it.
+func (c *SqliteClient) SetUserSetting(userId int64, key, value string) error {
+	if value == "" {
+		_, err := c.q.Exec("DELETE FROM user_setting WHERE user_id = ? AND name = ?", userId, key)
+		if err != nil {
+			return fmt.Errorf("setUserSetting delete %s of %d: %w", key, userId, err)
+		}
+		return nil
+	}
+	sqlQuery := "INSERT OR REPLACE INTO user_setting( user_id, name, value, updated_at) VALUES( ?, ?, ?, ?);"
+	_, err := c.q.Exec(sqlQuery, userId, key, value, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("setUserSetting %s of %d: %w", key, userId, err)
+	}
+	return nil
+}
+
+func (c *SqliteClient) ListUserSettings(userId int64) (schema.Settings, error) {
+	rows, err := c.q.Query("SELECT name, value FROM user_setting WHERE user_id = ?", userId)
+	if err != nil {
+		return nil, fmt.Errorf("listUserSettings %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	settings := schema.Settings{}
+	for rows.Next() {
+		var key, value string
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return nil, fmt.Errorf("listUserSettings scan: %w", err)
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
+
+func (c *SqliteClient) ListAllUserSettings() ([]schema.UserSetting, error) {
+	rows, err := c.q.Query("SELECT user_id, name, value FROM user_setting ORDER BY user_id, name")
+	if err != nil {
+		return nil, fmt.Errorf("listAllUserSettings: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.UserSetting
+	for rows.Next() {
+		var s schema.UserSetting
+		err = rows.Scan(&s.UserId, &s.Key, &s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("listAllUserSettings scan: %w", err)
+		}
+		ret = append(ret, s)
+	}
+	return ret, rows.Err()
+}
@@ -0,0 +1,51 @@
+package msqlclient
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// statements are prepared once for the queries workers run on every poll.
+type statements struct {
+	claimTask     *sql.Stmt
+	addTask       *sql.Stmt
+	getTaskById   *sql.Stmt
+	getDialogById *sql.Stmt
+}
+
+func prepareStatements(db *sql.DB) (*statements, error) {
+	s := &statements{}
+	for _, p := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.claimTask, claimTaskQuery},
+		{&s.addTask, addTaskQuery},
+		{&s.getTaskById, getTaskByIdQuery},
+		{&s.getDialogById, getDialogByIdQuery},
+	} {
+		stmt, err := db.Prepare(p.query)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("prepare %q: %w", p.query, err)
+		}
+		*p.stmt = stmt
+	}
+	return s, nil
+}
+
+func (s *statements) close() {
+	for _, stmt := range []*sql.Stmt{s.claimTask, s.addTask, s.getTaskById, s.getDialogById} {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+	}
+}
+
+// stmt returns s bound to the running transaction if there is one.
+func (c *SqliteClient) stmt(s *sql.Stmt) *sql.Stmt {
+	if tx, ok := c.q.(*sql.Tx); ok {
+		return tx.Stmt(s)
+	}
+	return s
+}
@@ -1,42 +1,133 @@
 package msqlclient
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"net/url"
 	"path"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
 )
 
 type SqliteClient struct {
 	db *sql.DB
+	// q is db or the running transaction
+	q     querier
+	stmts *statements
 }
 
-const (
-	dataPath = "data"
-)
+// Config is the sqlite part of mcore config.
+type Config struct {
+	DataDir         string        `default:"data" usage:"directory for the sqlite db file"`
+	FileName        string        `default:"sql.db" usage:"sqlite db file name"`
+	JournalMode     string        `default:"WAL" usage:"sqlite journal_mode, WAL lets readers work while one writes"`
+	BusyTimeout     time.Duration `default:"5s" usage:"how long a query waits for a locked db"`
+	Synchronous     string        `default:"NORMAL" usage:"sqlite synchronous level"`
+	MaxOpenConns    int           `default:"4" usage:"max open connections"`
+	MaxIdleConns    int           `default:"4" usage:"max idle connections"`
+	ConnMaxLifetime time.Duration `default:"0s" usage:"max connection lifetime, 0 is forever"`
+}
 
-func NewSqlClient(dbFileName string) *SqliteClient {
-	initDBIfNeeded(dataPath, dbFileName)
-	dbPath := path.Join(dataPath, dbFileName)
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
 
-	db, err := sql.Open("sqlite3", dbPath)
+func NewSqlClient(cfg Config) *SqliteClient {
+	db, err := sql.Open("sqlite3", dsn(cfg))
 	if err != nil {
 		log.Fatalf("no open db %s", err.Error())
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	initDBIfNeeded(cfg.DataDir, db)
 
-	var version string
+	var version, journalMode string
 	err = db.QueryRow("SELECT SQLITE_VERSION()").Scan(&version)
-
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = db.QueryRow("PRAGMA journal_mode").Scan(&journalMode)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Printf("sqlite version: %s", version)
+	log.Printf("sqlite version: %s, journal mode: %s", version, journalMode)
+
+	stmts, err := prepareStatements(db)
+	if err != nil {
+		log.Fatalf("prepare statements %s", err.Error())
+	}
 
 	return &SqliteClient{
-		db: db,
+		db:    db,
+		q:     db,
+		stmts: stmts,
 	}
 }
 
+// dsn passes the pragmas as go-sqlite3 params, so every connection
+// of the pool gets them.
+func dsn(cfg Config) string {
+	params := url.Values{}
+	// immediate transactions take the write lock on begin, so two
+	// transactions do not deadlock upgrading their read locks
+	params.Set("_txlock", "immediate")
+	params.Set("_journal_mode", cfg.JournalMode)
+	params.Set("_busy_timeout", fmt.Sprintf("%d", cfg.BusyTimeout.Milliseconds()))
+	params.Set("_synchronous", cfg.Synchronous)
+	return "file:" + path.Join(cfg.DataDir, cfg.FileName) + "?" + params.Encode()
+}
+
+func (c *SqliteClient) InTx(fn func(tx storage.Repo) error) error {
+	if _, ok := c.q.(*sql.Tx); ok {
+		return fn(c)
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("inTx begin: %w", err)
+	}
+	err = fn(&SqliteClient{db: c.db, q: tx, stmts: c.stmts})
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("inTx commit: %w", err)
+	}
+	return nil
+}
+
 func (c *SqliteClient) DbClose() {
+	c.stmts.close()
 	_ = c.db.Close()
 }
+
+// Ready checks that the db answers and that all migrations are applied.
+func (c *SqliteClient) Ready(ctx context.Context) error {
+	err := c.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("ready ping db: %w", err)
+	}
+
+	migrations, err := storage.LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+	version, err := schemaVersion(ctx, c.db)
+	if err != nil {
+		return fmt.Errorf("ready: %w", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+	if version != latest {
+		return fmt.Errorf("ready schema version %d, expected %d", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package msqlclient
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		c := NewSqlClient(Config{
+			DataDir:      t.TempDir(),
+			FileName:     "test.db",
+			JournalMode:  "WAL",
+			BusyTimeout:  5e9,
+			Synchronous:  "NORMAL",
+			MaxOpenConns: 4,
+			MaxIdleConns: 4,
+		})
+		t.Cleanup(c.DbClose)
+		return c
+	})
+}
@@ -5,33 +5,35 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
+	"time"
 )
 
-const creatTask = `
-CREATE TABLE IF NOT EXISTS task (
-	id integer NOT NULL PRIMARY KEY,
- 	dialog INTEGER NOT NULL,
-  	status INTEGER NOT NULL,
-  	type INTEGER NOT NULL,
-  	data blob null default (x'') 
-  );
+const (
+	addTaskQuery = `
+INSERT INTO task( dialog, status, type, data, created_at, updated_at)
+	VALUES( ?, ?, ?, ?, ?, ?);
+	`
+	getTaskByIdQuery = `
+select id, dialog, status, type, data, created_at, updated_at from task where id = ?
+`
+	claimTaskQuery = `
+UPDATE task SET status = ?, updated_at = ?
+	WHERE id = (SELECT id FROM task WHERE type = ? and status = ? ORDER BY ID LIMIT 1)
+	RETURNING id, dialog, status, type, data, created_at, updated_at
 `
+)
 
 func (c *SqliteClient) AddTask(task schema.Task) (int64, error) {
 	if task.Type == schema.TaskTypeUndefined {
 		return 0, errors.New("invalid task type")
 	}
 
-	sqlQuery := `
-INSERT INTO task( dialog, status, type, data)
-	VALUES( ?, ?, ?, ?);
-	`
-
 	data, err := task.TaskData.Marshal()
 	if err != nil {
 		return 0, fmt.Errorf("addtask task data marshal: %w", err)
 	}
-	res, err := c.db.Exec(sqlQuery, task.DialogId, task.Status, task.Type, data)
+	now := time.Now().UnixMilli()
+	res, err := c.stmt(c.stmts.addTask).Exec(task.DialogId, task.Status, task.Type, data, now, now)
 	if err != nil {
 		return 0, fmt.Errorf("insert addTask: %w", err)
 	}
@@ -48,9 +50,9 @@ func (c *SqliteClient) UpdateTaskStatus(task schema.Task) error {
 		return fmt.Errorf("smt is wrong try to updata task without id")
 	}
 
-	sqlQuery := "UPDATE task SET status = ? WHERE id = ?"
+	sqlQuery := "UPDATE task SET status = ?, updated_at = ? WHERE id = ?"
 
-	_, err := c.db.Exec(sqlQuery, task.Status, task.Id)
+	_, err := c.q.Exec(sqlQuery, task.Status, time.Now().UnixMilli(), task.Id)
 	if err != nil {
 		return fmt.Errorf("updateTaskStatus : %w", err)
 	}
@@ -58,36 +60,124 @@ func (c *SqliteClient) UpdateTaskStatus(task schema.Task) error {
 }
 
 func (c *SqliteClient) getTaskFromRow(row *sql.Row) (schema.Task, error) {
-	t := &schema.Task{}
-	var data []byte
-
-	err := row.Scan(&t.Id, &t.DialogId, &t.Status, &t.Type, &data)
+	t, err := scanTask(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return schema.Task{}, nil
 		}
-		return schema.Task{}, fmt.Errorf("getTaskFromRow scan %w", err)
+		return schema.Task{}, fmt.Errorf("getTaskFromRow %w", err)
+	}
+	return t, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row scanner) (schema.Task, error) {
+	t := schema.Task{}
+	var data []byte
+	var createdAt, updatedAt int64
+
+	err := row.Scan(&t.Id, &t.DialogId, &t.Status, &t.Type, &data, &createdAt, &updatedAt)
+	if err != nil {
+		return schema.Task{}, fmt.Errorf("scan task: %w", err)
 	}
+	t.CreatedAt = time.UnixMilli(createdAt)
+	t.UpdatedAt = time.UnixMilli(updatedAt)
 	err = t.TaskData.Unmarshal(data)
 	if err != nil {
-		return schema.Task{}, fmt.Errorf("getTaskFromRow unmarshal %w", err)
+		return schema.Task{}, fmt.Errorf("unmarshal task %d: %w", t.Id, err)
 	}
-	return *t, err
+	return t, nil
 }
 
 func (c *SqliteClient) GetTaskById(id int64) (schema.Task, error) {
+	return c.getTaskFromRow(c.stmt(c.stmts.getTaskById).QueryRow(id))
+}
+
+func (c *SqliteClient) ClaimTask(t schema.TaskType) (schema.Task, error) {
+	if t == schema.TaskTypeUndefined {
+		return schema.Task{}, fmt.Errorf("claimTask: wrong task type")
+	}
+	return c.getTaskFromRow(c.stmt(c.stmts.claimTask).QueryRow(schema.TaskStatusSended, time.Now().UnixMilli(), t, schema.TaskStatusCreate))
+}
+
+func (c *SqliteClient) ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error) {
 	sqlQuery := `
-select id, dialog, status, type, data from task where id = ?
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE status = ? ORDER BY id
 `
-	return c.getTaskFromRow(c.db.QueryRow(sqlQuery, id))
+	rows, err := c.q.Query(sqlQuery, status)
+	if err != nil {
+		return nil, fmt.Errorf("listTasksByStatus: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listTasksByStatus %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
 }
 
-func (c *SqliteClient) GetFirstTaskByType(t schema.TaskType) (schema.Task, error) {
-	if t == schema.TaskTypeUndefined {
-		return schema.Task{}, fmt.Errorf("getFirstTaskByType: wrong task type")
+func (c *SqliteClient) ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT t.id, t.dialog, t.status, t.type, t.data, t.created_at, t.updated_at FROM task t JOIN dialog d ON d.id = t.dialog
+WHERE d.user_id = ? AND t.status = ? ORDER BY t.id
+`
+	rows, err := c.q.Query(sqlQuery, userId, status)
+	if err != nil {
+		return nil, fmt.Errorf("listUserTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listUserTasks %w", err)
+		}
+		ret = append(ret, t)
 	}
+	return ret, rows.Err()
+}
+
+// CountOpenTasks needs no lock, transactions of sqlite begin immediate
+// and run one at a time.
+func (c *SqliteClient) CountOpenTasks(taskType schema.TaskType, userId int64) (int, int, error) {
 	sqlQuery := `
-SELECT id, dialog, status, type, data FROM task WHERE type = ? and status = ? ORDER BY ID LIMIT 1
+SELECT COUNT(*), COALESCE(SUM(CASE WHEN d.user_id = ? THEN 1 ELSE 0 END), 0) FROM task t LEFT JOIN dialog d ON d.id = t.dialog
+WHERE t.type = ? AND t.status IN (?, ?)
 `
-	return c.getTaskFromRow(c.db.QueryRow(sqlQuery, t, schema.TaskStatusCreate))
+	var all, mine int
+	err := c.q.QueryRow(sqlQuery, userId, taskType, schema.TaskStatusCreate, schema.TaskStatusSended).Scan(&all, &mine)
+	if err != nil {
+		return 0, 0, fmt.Errorf("countOpenTasks: %w", err)
+	}
+	return all, mine, nil
+}
+
+func (c *SqliteClient) ListDialogTasks(dialogId int64) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE dialog = ? ORDER BY id
+`
+	rows, err := c.q.Query(sqlQuery, dialogId)
+	if err != nil {
+		return nil, fmt.Errorf("listDialogTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listDialogTasks %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
 }
@@ -0,0 +1,25 @@
+package pgclient
+
+func (c *PgClient) DeleteAllTasks() error {
+	_, err := c.q.Exec("delete from task_event;")
+	if err != nil {
+		return err
+	}
+	_, err = c.q.Exec("delete from task_alert;")
+	if err != nil {
+		return err
+	}
+	sqlQuery := "delete from task;"
+	_, err = c.q.Exec(sqlQuery)
+	return err
+}
+
+func (c *PgClient) DeleteAllDialogs() error {
+	_, err := c.q.Exec("delete from dialog_ref;")
+	if err != nil {
+		return err
+	}
+	sqlQuery := "delete from dialog;"
+	_, err = c.q.Exec(sqlQuery)
+	return err
+}
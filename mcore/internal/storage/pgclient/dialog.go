@@ -0,0 +1,109 @@
+package pgclient
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *PgClient) AddDialog(d schema.Dialog) (int64, error) {
+	data, err := d.GetMessagesAsByte()
+	if err != nil {
+		return 0, fmt.Errorf("addDialog can't parse messages: %w", err)
+	}
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return 0, fmt.Errorf("addDialog can't marshal state: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	sqlQuery := `INSERT INTO dialog( key, dialogstatus, data, state, user_id, created_at, updated_at) VALUES( $1, $2, $3, $4, $5, $6, $7) RETURNING id;`
+	var id int64
+	err = c.q.QueryRow(sqlQuery, d.Key, d.DialogStatus, data, state, d.UserId(), now, now).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert addDialog: %w", err)
+	}
+
+	return id, nil
+}
+
+func (c *PgClient) GetDialogById(id int64) (schema.Dialog, error) {
+	sqlQuery := "SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE id = $1"
+	// read committed lets another transaction change the dialog before
+	// this one saves it, the lock makes it wait
+	if _, ok := c.q.(*sql.Tx); ok {
+		sqlQuery += " FOR UPDATE"
+	}
+
+	d, err := scanDialog(c.q.QueryRow(sqlQuery, id))
+	if err != nil {
+		return d, fmt.Errorf("getDialogById = %d %w", id, err)
+	}
+	return d, nil
+}
+
+func (c *PgClient) UpdateDialog(d schema.Dialog) error {
+	if d.Id == 0 {
+		return fmt.Errorf("updateDialog dialog.id is 0 nothink to update")
+	}
+
+	sqlQuery := "UPDATE dialog SET dialogstatus = $1, data = $2, state = $3, updated_at = $4 WHERE id = $5"
+	msgByte, err := d.GetMessagesAsByte()
+	if err != nil {
+		return fmt.Errorf("updateDialog cat't marshal messages %w", err)
+	}
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return fmt.Errorf("updateDialog can't marshal state %w", err)
+	}
+	_, err = c.q.Exec(sqlQuery, d.DialogStatus, msgByte, state, time.Now().UnixMilli(), d.Id)
+	if err != nil {
+		return fmt.Errorf("updateDialog : %w", err)
+	}
+	return nil
+}
+
+func (c *PgClient) ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error) {
+	sqlQuery := `
+SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+`
+	rows, err := c.q.Query(sqlQuery, userId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listUserDialogs: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Dialog
+	for rows.Next() {
+		d, err := scanDialog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listUserDialogs %w", err)
+		}
+		ret = append(ret, d)
+	}
+	return ret, rows.Err()
+}
+
+func scanDialog(row scanner) (schema.Dialog, error) {
+	d := schema.Dialog{}
+	var data, state []byte
+	var createdAt, updatedAt int64
+
+	err := row.Scan(&d.Id, &d.Key, &d.DialogStatus, &data, &state, &createdAt, &updatedAt)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("scan dialog: %w", err)
+	}
+	d.CreatedAt = time.UnixMilli(createdAt)
+	d.UpdatedAt = time.UnixMilli(updatedAt)
+	err = d.SetMessagesFromByte(data)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("unmarshal dialog %d: %w", d.Id, err)
+	}
+	err = d.SetStateFromByte(state)
+	if err != nil {
+		return schema.Dialog{}, fmt.Errorf("unmarshal dialog state %d: %w", d.Id, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,29 @@
+package pgclient
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (c *PgClient) AddDialogRef(chatId int64, messageId int, dialogId int64) error {
+	sqlQuery := "INSERT INTO dialog_ref( chat_id, message_id, dialog_id) VALUES( $1, $2, $3) ON CONFLICT (chat_id, message_id) DO UPDATE SET dialog_id = EXCLUDED.dialog_id;"
+	_, err := c.q.Exec(sqlQuery, chatId, messageId, dialogId)
+	if err != nil {
+		return fmt.Errorf("addDialogRef %d: %w", dialogId, err)
+	}
+	return nil
+}
+
+// GetDialogIdByRef returns 0 when the message belongs to no dialog.
+func (c *PgClient) GetDialogIdByRef(chatId int64, messageId int) (int64, error) {
+	var dialogId int64
+	err := c.q.QueryRow("SELECT dialog_id FROM dialog_ref WHERE chat_id = $1 AND message_id = $2", chatId, messageId).Scan(&dialogId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getDialogIdByRef %d/%d: %w", chatId, messageId, err)
+	}
+	return dialogId, nil
+}
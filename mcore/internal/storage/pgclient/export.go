@@ -0,0 +1,95 @@
+package pgclient
+
+import (
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *PgClient) ListTasks(afterId int64, limit int) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE id > $1 ORDER BY id LIMIT $2
+`
+	rows, err := c.q.Query(sqlQuery, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listTasks %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
+}
+
+func (c *PgClient) ListDialogs(afterId int64, limit int) ([]schema.Dialog, error) {
+	sqlQuery := `
+SELECT id, key, dialogstatus, data, state, created_at, updated_at FROM dialog WHERE id > $1 ORDER BY id LIMIT $2
+`
+	rows, err := c.q.Query(sqlQuery, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listDialogs: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Dialog
+	for rows.Next() {
+		d, err := scanDialog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listDialogs %w", err)
+		}
+		ret = append(ret, d)
+	}
+	return ret, rows.Err()
+}
+
+// RestoreTask moves the id sequence past the restored id,
+// so new tasks do not collide with it.
+func (c *PgClient) RestoreTask(task schema.Task) error {
+	data, err := task.TaskData.Marshal()
+	if err != nil {
+		return fmt.Errorf("restoreTask task data marshal: %w", err)
+	}
+	sqlQuery := `
+INSERT INTO task( id, dialog, status, type, data, created_at, updated_at)
+	VALUES( $1, $2, $3, $4, $5, $6, $7);
+`
+	_, err = c.q.Exec(sqlQuery, task.Id, task.DialogId, task.Status, task.Type, data,
+		task.CreatedAt.UnixMilli(), task.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("restoreTask %d: %w", task.Id, err)
+	}
+	_, err = c.q.Exec("SELECT setval(pg_get_serial_sequence('task', 'id'), (SELECT MAX(id) FROM task))")
+	if err != nil {
+		return fmt.Errorf("restoreTask sequence: %w", err)
+	}
+	return nil
+}
+
+func (c *PgClient) RestoreDialog(d schema.Dialog) error {
+	data, err := d.GetMessagesAsByte()
+	if err != nil {
+		return fmt.Errorf("restoreDialog can't parse messages: %w", err)
+	}
+	state, err := d.GetStateAsByte()
+	if err != nil {
+		return fmt.Errorf("restoreDialog can't marshal state: %w", err)
+	}
+	sqlQuery := `
+INSERT INTO dialog( id, key, dialogstatus, data, state, user_id, created_at, updated_at) VALUES( $1, $2, $3, $4, $5, $6, $7, $8);
+`
+	_, err = c.q.Exec(sqlQuery, d.Id, d.Key, d.DialogStatus, data, state, d.UserId(), d.CreatedAt.UnixMilli(), d.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("restoreDialog %d: %w", d.Id, err)
+	}
+	_, err = c.q.Exec("SELECT setval(pg_get_serial_sequence('dialog', 'id'), (SELECT MAX(id) FROM dialog))")
+	if err != nil {
+		return fmt.Errorf("restoreDialog sequence: %w", err)
+	}
+	return nil
+}
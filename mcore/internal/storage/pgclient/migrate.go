@@ -0,0 +1,79 @@
+package pgclient
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockId is the advisory lock key held while migrations run.
+const migrationLockId = 6336001
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at BIGINT NOT NULL
+);
+`
+
+// migrate applies pending migrations in one transaction under an advisory
+// lock, so several mcore instances can start at the same time.
+func migrate(ctx context.Context, db *sql.DB) error {
+	migrations, err := storage.LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migrate begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockId)
+	if err != nil {
+		return fmt.Errorf("migrate lock: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, createSchemaMigrations)
+	if err != nil {
+		return fmt.Errorf("migrate create schema_migrations: %w", err)
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("migrate schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, m.Query)
+		if err != nil {
+			return fmt.Errorf("migrate apply %s: %w", m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, name, applied_at) VALUES($1, $2, $3)",
+			m.Version, m.Name, time.Now().UnixMilli())
+		if err != nil {
+			return fmt.Errorf("migrate save %s: %w", m.Name, err)
+		}
+		log.Printf("migration applied: %s", m.Name)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("migrate commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS task (
+	id BIGSERIAL PRIMARY KEY,
+	dialog BIGINT NOT NULL,
+	status INTEGER NOT NULL,
+	type INTEGER NOT NULL,
+	data BYTEA NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS dialog (
+	id BIGSERIAL PRIMARY KEY,
+	key TEXT NOT NULL,
+	dialogstatus INTEGER NOT NULL,
+	data BYTEA NOT NULL DEFAULT ''
+);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS task_type_status_idx ON task (type, status, id);
+CREATE INDEX IF NOT EXISTS task_dialog_idx ON task (dialog);
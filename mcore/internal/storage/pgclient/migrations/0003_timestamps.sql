@@ -0,0 +1,5 @@
+-- unix milliseconds, same as in sqlite
+ALTER TABLE task ADD COLUMN IF NOT EXISTS created_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE task ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE dialog ADD COLUMN IF NOT EXISTS created_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE dialog ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_event (
+	id BIGSERIAL PRIMARY KEY,
+	task_id BIGINT NOT NULL,
+	task_type INTEGER NOT NULL,
+	command TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL,
+	worker TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_event_created_idx ON task_event(created_at);
+CREATE INDEX IF NOT EXISTS task_event_task_idx ON task_event(task_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS task_alert (
+	task_id BIGINT NOT NULL,
+	status INTEGER NOT NULL,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY (task_id, status)
+);
@@ -0,0 +1,9 @@
+ALTER TABLE dialog ADD COLUMN IF NOT EXISTS state BYTEA NOT NULL DEFAULT ('');
+
+-- bot messages of a dialog by telegram chat and message id, a reply to one continues the dialog
+CREATE TABLE IF NOT EXISTS dialog_ref (
+	chat_id BIGINT NOT NULL,
+	message_id BIGINT NOT NULL,
+	dialog_id BIGINT NOT NULL,
+	PRIMARY KEY (chat_id, message_id)
+);
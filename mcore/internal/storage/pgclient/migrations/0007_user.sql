@@ -0,0 +1,10 @@
+-- bot_user, user is a keyword in postgres
+CREATE TABLE IF NOT EXISTS bot_user (
+	id BIGINT PRIMARY KEY,
+	user_name TEXT NOT NULL DEFAULT '',
+	chat_id BIGINT NOT NULL DEFAULT 0,
+	role TEXT NOT NULL DEFAULT '',
+	status INTEGER NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_setting (
+	user_id BIGINT NOT NULL,
+	name TEXT NOT NULL,
+	value TEXT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	PRIMARY KEY (user_id, name)
+);
@@ -0,0 +1,4 @@
+-- the user who began the dialog, /status and /history list dialogs by it
+ALTER TABLE dialog ADD COLUMN IF NOT EXISTS user_id BIGINT NOT NULL DEFAULT 0;
+UPDATE dialog SET user_id = coalesce((convert_from(data, 'UTF8')::jsonb -> 0 ->> 'userId')::bigint, 0) WHERE data <> '';
+CREATE INDEX IF NOT EXISTS dialog_user_idx ON dialog (user_id, id);
@@ -0,0 +1,101 @@
+package pgclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type PgClient struct {
+	db *sql.DB
+	// q is db or the running transaction
+	q querier
+}
+
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func NewPgClient(dsn string) (*PgClient, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var version string
+	err = db.QueryRowContext(ctx, "SHOW server_version").Scan(&version)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("postgres version: %w", err)
+	}
+	log.Printf("postgres version: %s", version)
+
+	err = migrate(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &PgClient{
+		db: db,
+		q:  db,
+	}, nil
+}
+
+func (c *PgClient) InTx(fn func(tx storage.Repo) error) error {
+	if _, ok := c.q.(*sql.Tx); ok {
+		return fn(c)
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("inTx begin: %w", err)
+	}
+	err = fn(&PgClient{db: c.db, q: tx})
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("inTx commit: %w", err)
+	}
+	return nil
+}
+
+func (c *PgClient) DbClose() {
+	_ = c.db.Close()
+}
+
+// Ready checks that the db answers and that all migrations are applied.
+func (c *PgClient) Ready(ctx context.Context) error {
+	err := c.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("ready ping db: %w", err)
+	}
+
+	migrations, err := storage.LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+	var version int
+	err = c.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("ready schema version: %w", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+	if version != latest {
+		return fmt.Errorf("ready schema version %d, expected %d", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package pgclient
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	dsn := storagetest.PostgresDsn(t)
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		c, err := NewPgClient(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.DbClose)
+		// the db is shared between checks, each of them starts empty
+		_, err = c.db.Exec(`TRUNCATE task, dialog, task_event, task_alert, dialog_ref,
+			bot_user, user_setting, user_alias RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	})
+}
@@ -0,0 +1,194 @@
+package pgclient
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *PgClient) AddTask(task schema.Task) (int64, error) {
+	if task.Type == schema.TaskTypeUndefined {
+		return 0, errors.New("invalid task type")
+	}
+
+	sqlQuery := `
+INSERT INTO task( dialog, status, type, data, created_at, updated_at)
+	VALUES( $1, $2, $3, $4, $5, $6) RETURNING id;
+	`
+
+	data, err := task.TaskData.Marshal()
+	if err != nil {
+		return 0, fmt.Errorf("addtask task data marshal: %w", err)
+	}
+	now := time.Now().UnixMilli()
+	var id int64
+	err = c.q.QueryRow(sqlQuery, task.DialogId, task.Status, task.Type, data, now, now).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert addTask: %w", err)
+	}
+
+	return id, nil
+}
+
+func (c *PgClient) UpdateTaskStatus(task schema.Task) error {
+	if task.Id == 0 {
+		return fmt.Errorf("smt is wrong try to updata task without id")
+	}
+
+	sqlQuery := "UPDATE task SET status = $1, updated_at = $2 WHERE id = $3"
+
+	_, err := c.q.Exec(sqlQuery, task.Status, time.Now().UnixMilli(), task.Id)
+	if err != nil {
+		return fmt.Errorf("updateTaskStatus : %w", err)
+	}
+	return nil
+}
+
+func (c *PgClient) getTaskFromRow(row *sql.Row) (schema.Task, error) {
+	t, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return schema.Task{}, nil
+		}
+		return schema.Task{}, fmt.Errorf("getTaskFromRow %w", err)
+	}
+	return t, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row scanner) (schema.Task, error) {
+	t := schema.Task{}
+	var data []byte
+	var createdAt, updatedAt int64
+
+	err := row.Scan(&t.Id, &t.DialogId, &t.Status, &t.Type, &data, &createdAt, &updatedAt)
+	if err != nil {
+		return schema.Task{}, fmt.Errorf("scan task: %w", err)
+	}
+	t.CreatedAt = time.UnixMilli(createdAt)
+	t.UpdatedAt = time.UnixMilli(updatedAt)
+	err = t.TaskData.Unmarshal(data)
+	if err != nil {
+		return schema.Task{}, fmt.Errorf("unmarshal task %d: %w", t.Id, err)
+	}
+	return t, nil
+}
+
+func (c *PgClient) GetTaskById(id int64) (schema.Task, error) {
+	sqlQuery := `
+select id, dialog, status, type, data, created_at, updated_at from task where id = $1
+`
+	return c.getTaskFromRow(c.q.QueryRow(sqlQuery, id))
+}
+
+// ClaimTask skips rows locked by a concurrent claim, so parallel workers
+// of one type do not wait for each other and never share a task.
+func (c *PgClient) ClaimTask(t schema.TaskType) (schema.Task, error) {
+	if t == schema.TaskTypeUndefined {
+		return schema.Task{}, fmt.Errorf("claimTask: wrong task type")
+	}
+	sqlQuery := `
+UPDATE task SET status = $1, updated_at = $2
+	WHERE id = (
+		SELECT id FROM task WHERE type = $3 and status = $4
+		ORDER BY id LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, dialog, status, type, data, created_at, updated_at
+`
+	return c.getTaskFromRow(c.q.QueryRow(sqlQuery, schema.TaskStatusSended, time.Now().UnixMilli(), t, schema.TaskStatusCreate))
+}
+
+func (c *PgClient) ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE status = $1 ORDER BY id
+`
+	rows, err := c.q.Query(sqlQuery, status)
+	if err != nil {
+		return nil, fmt.Errorf("listTasksByStatus: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listTasksByStatus %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
+}
+
+func (c *PgClient) ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT t.id, t.dialog, t.status, t.type, t.data, t.created_at, t.updated_at FROM task t JOIN dialog d ON d.id = t.dialog
+WHERE d.user_id = $1 AND t.status = $2 ORDER BY t.id
+`
+	rows, err := c.q.Query(sqlQuery, userId, status)
+	if err != nil {
+		return nil, fmt.Errorf("listUserTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listUserTasks %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
+}
+
+// quotaLock is the first key of the advisory locks of task types.
+const quotaLock = 4801
+
+func (c *PgClient) CountOpenTasks(taskType schema.TaskType, userId int64) (int, int, error) {
+	// read committed lets two transactions count the same tasks, the lock
+	// makes the second one wait for the insert of the first
+	if _, ok := c.q.(*sql.Tx); ok {
+		_, err := c.q.Exec("SELECT pg_advisory_xact_lock($1, $2)", quotaLock, int(taskType))
+		if err != nil {
+			return 0, 0, fmt.Errorf("countOpenTasks lock: %w", err)
+		}
+	}
+	sqlQuery := `
+SELECT COUNT(*), COUNT(*) FILTER (WHERE d.user_id = $1) FROM task t LEFT JOIN dialog d ON d.id = t.dialog
+WHERE t.type = $2 AND t.status IN ($3, $4)
+`
+	var all, mine int
+	err := c.q.QueryRow(sqlQuery, userId, taskType, schema.TaskStatusCreate, schema.TaskStatusSended).Scan(&all, &mine)
+	if err != nil {
+		return 0, 0, fmt.Errorf("countOpenTasks: %w", err)
+	}
+	return all, mine, nil
+}
+
+func (c *PgClient) ListDialogTasks(dialogId int64) ([]schema.Task, error) {
+	sqlQuery := `
+SELECT id, dialog, status, type, data, created_at, updated_at FROM task WHERE dialog = $1 ORDER BY id
+`
+	rows, err := c.q.Query(sqlQuery, dialogId)
+	if err != nil {
+		return nil, fmt.Errorf("listDialogTasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.Task
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listDialogTasks %w", err)
+		}
+		ret = append(ret, t)
+	}
+	return ret, rows.Err()
+}
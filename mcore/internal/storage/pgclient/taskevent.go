@@ -0,0 +1,48 @@
+package pgclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *PgClient) AddTaskEvent(e schema.TaskEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	sqlQuery := `
+INSERT INTO task_event( task_id, task_type, command, kind, worker, message, created_at)
+	VALUES( $1, $2, $3, $4, $5, $6, $7);
+`
+	_, err := c.q.Exec(sqlQuery, e.TaskId, e.TaskType, e.Command, e.Kind, e.Worker, e.Message, e.CreatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("addTaskEvent: %w", err)
+	}
+	return nil
+}
+
+func (c *PgClient) ListTaskEvents(since time.Time) ([]schema.TaskEvent, error) {
+	sqlQuery := `
+SELECT id, task_id, task_type, command, kind, worker, message, created_at FROM task_event
+	WHERE created_at >= $1 ORDER BY id
+`
+	rows, err := c.q.Query(sqlQuery, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("listTaskEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.TaskEvent
+	for rows.Next() {
+		var e schema.TaskEvent
+		var createdAt int64
+		err = rows.Scan(&e.Id, &e.TaskId, &e.TaskType, &e.Command, &e.Kind, &e.Worker, &e.Message, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("listTaskEvents scan: %w", err)
+		}
+		e.CreatedAt = time.UnixMilli(createdAt)
+		ret = append(ret, e)
+	}
+	return ret, rows.Err()
+}
@@ -0,0 +1,81 @@
+package pgclient
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (c *PgClient) AddUser(u schema.User) error {
+	now := time.Now()
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = now
+	}
+	if u.UpdatedAt.IsZero() {
+		u.UpdatedAt = now
+	}
+	sqlQuery := "INSERT INTO bot_user( id, user_name, chat_id, role, status, created_at, updated_at) VALUES( $1, $2, $3, $4, $5, $6, $7);"
+	_, err := c.q.Exec(sqlQuery, u.Id, u.UserName, u.ChatId, u.Role, u.Status, u.CreatedAt.UnixMilli(), u.UpdatedAt.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("addUser %d: %w", u.Id, err)
+	}
+	return nil
+}
+
+// GetUser returns a zero user when there is none with the id.
+func (c *PgClient) GetUser(id int64) (schema.User, error) {
+	row := c.q.QueryRow("SELECT id, user_name, chat_id, role, status, created_at, updated_at FROM bot_user WHERE id = $1", id)
+	u, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return schema.User{}, nil
+	}
+	if err != nil {
+		return schema.User{}, fmt.Errorf("getUser %d: %w", id, err)
+	}
+	return u, nil
+}
+
+func (c *PgClient) UpdateUser(u schema.User) error {
+	if u.Id == 0 {
+		return fmt.Errorf("updateUser user.id is 0 nothink to update")
+	}
+	sqlQuery := "UPDATE bot_user SET user_name = $1, chat_id = $2, role = $3, status = $4, updated_at = $5 WHERE id = $6"
+	_, err := c.q.Exec(sqlQuery, u.UserName, u.ChatId, u.Role, u.Status, time.Now().UnixMilli(), u.Id)
+	if err != nil {
+		return fmt.Errorf("updateUser %d: %w", u.Id, err)
+	}
+	return nil
+}
+
+func (c *PgClient) ListUsers() ([]schema.User, error) {
+	rows, err := c.q.Query("SELECT id, user_name, chat_id, role, status, created_at, updated_at FROM bot_user ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("listUsers: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []schema.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listUsers scan: %w", err)
+		}
+		ret = append(ret, u)
+	}
+	return ret, rows.Err()
+}
+
+func scanUser(row scanner) (schema.User, error) {
+	var u schema.User
+	var createdAt, updatedAt int64
+	err := row.Scan(&u.Id, &u.UserName, &u.ChatId, &u.Role, &u.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return schema.User{}, err
+	}
+	u.CreatedAt = time.UnixMilli(createdAt)
+	u.UpdatedAt = time.UnixMilli(updatedAt)
+	return u, nil
+}
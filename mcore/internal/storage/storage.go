@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// Repo holds the data operations of a storage backend, managers take
+// the subset they need through their own repo interfaces.
+type Repo interface {
+	AddTask(task schema.Task) (int64, error)
+	// ClaimTask moves the oldest created task of type t to sended and
+	// returns it, two workers never get the same task.
+	ClaimTask(t schema.TaskType) (schema.Task, error)
+	GetTaskById(id int64) (schema.Task, error)
+	// ListTasksByStatus returns all tasks in status, oldest first.
+	ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error)
+	// ListUserTasks returns tasks in status of the dialogs the user began,
+	// oldest first.
+	ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error)
+	// CountOpenTasks counts tasks of taskType in create or sended, mine are
+	// the ones of dialogs the user began. In a transaction it keeps other
+	// counts of the type waiting until the transaction ends, so a check
+	// and the insert of a task happen at once.
+	CountOpenTasks(taskType schema.TaskType, userId int64) (all, mine int, err error)
+	// ListDialogTasks returns all tasks of the dialog, oldest first.
+	ListDialogTasks(dialogId int64) ([]schema.Task, error)
+	UpdateTaskStatus(task schema.Task) error
+
+	AddDialog(dialog schema.Dialog) (int64, error)
+	GetDialogById(id int64) (schema.Dialog, error)
+	UpdateDialog(d schema.Dialog) error
+	// ListUserDialogs returns up to limit dialogs the user began, newest first.
+	ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error)
+	// AddDialogRef links a telegram message of the bot to its dialog,
+	// GetDialogIdByRef returns 0 for messages of no dialog.
+	AddDialogRef(chatId int64, messageId int, dialogId int64) error
+	GetDialogIdByRef(chatId int64, messageId int) (int64, error)
+
+	// AddUser fails when the user is already there, GetUser returns
+	// a zero user when it is not.
+	AddUser(u schema.User) error
+	GetUser(id int64) (schema.User, error)
+	UpdateUser(u schema.User) error
+	// ListUsers returns all users ordered by id.
+	ListUsers() ([]schema.User, error)
+	// SetUserSetting deletes the setting when value is empty.
+	SetUserSetting(userId int64, key, value string) error
+	ListUserSettings(userId int64) (schema.Settings, error)
+	// ListAllUserSettings returns settings of all users ordered by user and key.
+	ListAllUserSettings() ([]schema.UserSetting, error)
+	// SetUserAlias deletes the alias when expansion is empty.
+	SetUserAlias(userId int64, name, expansion string) error
+	ListUserAliases(userId int64) (schema.Aliases, error)
+	// ListAllUserAliases returns aliases of all users ordered by user and name.
+	ListAllUserAliases() ([]schema.UserAlias, error)
+
+	// ListTasks and ListDialogs page through all rows ordered by id,
+	// starting after afterId.
+	ListTasks(afterId int64, limit int) ([]schema.Task, error)
+	ListDialogs(afterId int64, limit int) ([]schema.Dialog, error)
+	// RestoreTask and RestoreDialog insert rows as they are,
+	// keeping ids and timestamps.
+	RestoreTask(task schema.Task) error
+	RestoreDialog(d schema.Dialog) error
+
+	// AddTaskEvent appends to the task log, events are never updated.
+	AddTaskEvent(e schema.TaskEvent) error
+	// ListTaskEvents returns events created at or after since, oldest first.
+	ListTaskEvents(since time.Time) ([]schema.TaskEvent, error)
+
+	AddTaskAlert(a schema.TaskAlert) error
+	ListTaskAlerts() ([]schema.TaskAlert, error)
+	DeleteTaskAlert(taskId int64, status schema.TaskStatus) error
+
+	// DeleteAllTasks deletes tasks together with their events and alerts.
+	DeleteAllTasks() error
+	// DeleteAllDialogs deletes dialogs together with their refs.
+	DeleteAllDialogs() error
+}
+
+// Storage is implemented by every storage backend.
+type Storage interface {
+	Repo
+	// InTx runs fn in a transaction: it is committed when fn returns nil
+	// and rolled back otherwise.
+	InTx(fn func(tx Repo) error) error
+
+	Ready(ctx context.Context) error
+	DbClose()
+}
+
+// TxRepo is a Repo inside a running transaction,
+// InTx on it joins the transaction instead of starting a new one.
+type TxRepo struct {
+	Repo
+}
+
+func (t TxRepo) InTx(fn func(tx Repo) error) error {
+	return fn(t.Repo)
+}
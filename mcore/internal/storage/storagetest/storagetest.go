@@ -0,0 +1,788 @@
+// Package storagetest is a conformance suite for storage backends.
+// Every backend runs the same checks:
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return newEmptyStorage(t)
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// PostgresDsnEnv names the env variable with a dsn of a local postgres
+// for tests. Postgres tests are skipped when it is empty.
+const PostgresDsnEnv = "MCORE_TEST_POSTGRES_DSN"
+
+// PostgresDsn returns the test dsn or skips the test.
+func PostgresDsn(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv(PostgresDsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, no local postgres", PostgresDsnEnv)
+	}
+	return dsn
+}
+
+// Run runs the suite, newStorage must return an empty storage
+// and is called once per check.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	checks := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"Ready", testReady},
+		{"AddGetTask", testAddGetTask},
+		{"AddTaskUndefinedType", testAddTaskUndefinedType},
+		{"GetUnknownTask", testGetUnknownTask},
+		{"UpdateTaskStatus", testUpdateTaskStatus},
+		{"ClaimTaskOrder", testClaimTaskOrder},
+		{"ClaimTaskConcurrent", testClaimTaskConcurrent},
+		{"AddGetDialog", testAddGetDialog},
+		{"UpdateDialog", testUpdateDialog},
+		{"DialogState", testDialogState},
+		{"DialogRefs", testDialogRefs},
+		{"Users", testUsers},
+		{"UserSettings", testUserSettings},
+		{"UserAliases", testUserAliases},
+		{"DeleteAll", testDeleteAll},
+		{"ListTasks", testListTasks},
+		{"RestoreAndContinue", testRestoreAndContinue},
+		{"TaskEvents", testTaskEvents},
+		{"ListTasksByStatus", testListTasksByStatus},
+		{"UserDialogsAndTasks", testUserDialogsAndTasks},
+		{"TaskAlerts", testTaskAlerts},
+		{"InTxCommit", testInTxCommit},
+		{"InTxRollback", testInTxRollback},
+	}
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(s.DbClose)
+			c.fn(t, s)
+		})
+	}
+}
+
+func newTask(dialogId int64, taskType schema.TaskType) schema.Task {
+	return schema.Task{
+		DialogId: dialogId,
+		Status:   schema.TaskStatusCreate,
+		Type:     taskType,
+		TaskData: schema.TaskData{
+			Tn: schema.TaskNote{Command: schema.TaskNoteCmdAddInbox, AddText: "milk"},
+		},
+	}
+}
+
+func mustAddTask(t *testing.T, s storage.Storage, task schema.Task) int64 {
+	t.Helper()
+	id, err := s.AddTask(task)
+	if err != nil {
+		t.Fatalf("addTask: %v", err)
+	}
+	if id == 0 {
+		t.Fatalf("addTask returned id 0")
+	}
+	return id
+}
+
+func testReady(t *testing.T, s storage.Storage) {
+	err := s.Ready(context.Background())
+	if err != nil {
+		t.Fatalf("ready: %v", err)
+	}
+}
+
+func testAddGetTask(t *testing.T, s storage.Storage) {
+	id := mustAddTask(t, s, newTask(7, schema.TaskTypeNote))
+
+	got, err := s.GetTaskById(id)
+	if err != nil {
+		t.Fatalf("getTaskById: %v", err)
+	}
+	if got.Id != id || got.DialogId != 7 || got.Type != schema.TaskTypeNote || got.Status != schema.TaskStatusCreate {
+		t.Fatalf("getTaskById got %+v", got)
+	}
+	if got.TaskData.Tn.AddText != "milk" {
+		t.Fatalf("task data not saved: %+v", got.TaskData)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Fatalf("task timestamps not set: %+v", got)
+	}
+}
+
+func testAddTaskUndefinedType(t *testing.T, s storage.Storage) {
+	_, err := s.AddTask(newTask(1, schema.TaskTypeUndefined))
+	if err == nil {
+		t.Fatalf("addTask with undefined type must fail")
+	}
+}
+
+func testGetUnknownTask(t *testing.T, s storage.Storage) {
+	got, err := s.GetTaskById(424242)
+	if err != nil {
+		t.Fatalf("getTaskById: %v", err)
+	}
+	if got.Id != 0 {
+		t.Fatalf("unknown task must be empty, got %+v", got)
+	}
+}
+
+func testUpdateTaskStatus(t *testing.T, s storage.Storage) {
+	id := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+
+	err := s.UpdateTaskStatus(schema.Task{Id: id, Status: schema.TaskStatusDone})
+	if err != nil {
+		t.Fatalf("updateTaskStatus: %v", err)
+	}
+	got, err := s.GetTaskById(id)
+	if err != nil {
+		t.Fatalf("getTaskById: %v", err)
+	}
+	if got.Status != schema.TaskStatusDone {
+		t.Fatalf("status %d, want %d", got.Status, schema.TaskStatusDone)
+	}
+
+	err = s.UpdateTaskStatus(schema.Task{Status: schema.TaskStatusDone})
+	if err == nil {
+		t.Fatalf("updateTaskStatus without id must fail")
+	}
+}
+
+func testClaimTaskOrder(t *testing.T, s storage.Storage) {
+	first := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+	mustAddTask(t, s, newTask(2, schema.TaskTypeYtdl))
+	second := mustAddTask(t, s, newTask(3, schema.TaskTypeNote))
+	done := newTask(4, schema.TaskTypeNote)
+	done.Status = schema.TaskStatusDone
+	mustAddTask(t, s, done)
+
+	for _, want := range []int64{first, second} {
+		got, err := s.ClaimTask(schema.TaskTypeNote)
+		if err != nil {
+			t.Fatalf("claimTask: %v", err)
+		}
+		if got.Id != want {
+			t.Fatalf("claimTask id %d, want %d", got.Id, want)
+		}
+		if got.Status != schema.TaskStatusSended {
+			t.Fatalf("claimed task status %d, want %d", got.Status, schema.TaskStatusSended)
+		}
+	}
+
+	got, err := s.ClaimTask(schema.TaskTypeNote)
+	if err != nil {
+		t.Fatalf("claimTask: %v", err)
+	}
+	if got.Id != 0 {
+		t.Fatalf("no tasks left, claimed %+v", got)
+	}
+
+	_, err = s.ClaimTask(schema.TaskTypeUndefined)
+	if err == nil {
+		t.Fatalf("claimTask with undefined type must fail")
+	}
+}
+
+func testClaimTaskConcurrent(t *testing.T, s storage.Storage) {
+	const tasks, workers = 20, 4
+	for i := 0; i < tasks; i++ {
+		mustAddTask(t, s, newTask(int64(i+1), schema.TaskTypeYtdl))
+	}
+
+	var mu sync.Mutex
+	claimed := map[int64]int{}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, err := s.ClaimTask(schema.TaskTypeYtdl)
+				if err != nil {
+					t.Errorf("claimTask: %v", err)
+					return
+				}
+				if task.Id == 0 {
+					return
+				}
+				mu.Lock()
+				claimed[task.Id]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != tasks {
+		t.Fatalf("claimed %d tasks, want %d", len(claimed), tasks)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Fatalf("task %d claimed %d times", id, n)
+		}
+	}
+}
+
+func newDialog() schema.Dialog {
+	m := schema.Message{UserName: "testUser", MessageId: 10, ChatId: 1, Text: "/ping", Type: schema.MessageTypeUser}
+	return schema.Dialog{
+		Key:          schema.GenerateKey(m),
+		DialogStatus: schema.DialogStatusBegin,
+		Messages:     []schema.Message{m},
+	}
+}
+
+func testAddGetDialog(t *testing.T, s storage.Storage) {
+	d := newDialog()
+	id, err := s.AddDialog(d)
+	if err != nil {
+		t.Fatalf("addDialog: %v", err)
+	}
+
+	got, err := s.GetDialogById(id)
+	if err != nil {
+		t.Fatalf("getDialogById: %v", err)
+	}
+	if got.Id != id || got.Key != d.Key || got.DialogStatus != schema.DialogStatusBegin {
+		t.Fatalf("getDialogById got %+v", got)
+	}
+	if len(got.Messages) != 1 || got.Messages[0] != d.Messages[0] {
+		t.Fatalf("messages not saved: %+v", got.Messages)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Fatalf("dialog timestamps not set: %+v", got)
+	}
+
+	_, err = s.GetDialogById(id + 1000)
+	if err == nil {
+		t.Fatalf("getDialogById of unknown dialog must fail")
+	}
+}
+
+func testUpdateDialog(t *testing.T, s storage.Storage) {
+	id, err := s.AddDialog(newDialog())
+	if err != nil {
+		t.Fatalf("addDialog: %v", err)
+	}
+	d, err := s.GetDialogById(id)
+	if err != nil {
+		t.Fatalf("getDialogById: %v", err)
+	}
+
+	d.DialogStatus = schema.DialogStatusClose
+	d.Messages = append(d.Messages, schema.Message{ChatId: 1, Text: "Pong", Type: schema.MessageTypeBot})
+	err = s.UpdateDialog(d)
+	if err != nil {
+		t.Fatalf("updateDialog: %v", err)
+	}
+
+	got, err := s.GetDialogById(id)
+	if err != nil {
+		t.Fatalf("getDialogById: %v", err)
+	}
+	if got.DialogStatus != schema.DialogStatusClose || len(got.Messages) != 2 {
+		t.Fatalf("dialog not updated: %+v", got)
+	}
+
+	err = s.UpdateDialog(schema.Dialog{})
+	if err == nil {
+		t.Fatalf("updateDialog without id must fail")
+	}
+}
+
+func testDialogState(t *testing.T, s storage.Storage) {
+	id, err := s.AddDialog(newDialog())
+	if err != nil {
+		t.Fatalf("addDialog: %v", err)
+	}
+	d, err := s.GetDialogById(id)
+	if err != nil {
+		t.Fatalf("getDialogById: %v", err)
+	}
+	if len(d.State.Path) != 0 || d.State.Ask != "" {
+		t.Fatalf("new dialog has state: %+v", d.State)
+	}
+
+	d.DialogStatus = schema.DialogStatusWaitReply
+	d.State = schema.DialogState{
+		Path: []string{"ds", "add"},
+		Args: map[string]string{"url": "http://x"},
+		Ask:  "category",
+	}
+	err = s.UpdateDialog(d)
+	if err != nil {
+		t.Fatalf("updateDialog: %v", err)
+	}
+	d.State.Args["url"] = "changed"
+
+	got, err := s.GetDialogById(id)
+	if err != nil {
+		t.Fatalf("getDialogById: %v", err)
+	}
+	if got.DialogStatus != schema.DialogStatusWaitReply || got.State.Ask != "category" ||
+		len(got.State.Path) != 2 || got.State.Args["url"] != "http://x" {
+		t.Fatalf("dialog state not saved: %+v", got)
+	}
+}
+
+func testDialogRefs(t *testing.T, s storage.Storage) {
+	id, err := s.AddDialog(newDialog())
+	if err != nil {
+		t.Fatalf("addDialog: %v", err)
+	}
+	err = s.AddDialogRef(1, 11, id)
+	if err != nil {
+		t.Fatalf("addDialogRef: %v", err)
+	}
+
+	got, err := s.GetDialogIdByRef(1, 11)
+	if err != nil || got != id {
+		t.Fatalf("getDialogIdByRef got %d %v", got, err)
+	}
+	got, err = s.GetDialogIdByRef(2, 11)
+	if err != nil || got != 0 {
+		t.Fatalf("getDialogIdByRef of other chat got %d %v", got, err)
+	}
+
+	err = s.DeleteAllDialogs()
+	if err != nil {
+		t.Fatalf("deleteAllDialogs: %v", err)
+	}
+	got, err = s.GetDialogIdByRef(1, 11)
+	if err != nil || got != 0 {
+		t.Fatalf("ref left after deleteAllDialogs: %d %v", got, err)
+	}
+}
+
+func testUsers(t *testing.T, s storage.Storage) {
+	got, err := s.GetUser(42)
+	if err != nil || got.Id != 0 {
+		t.Fatalf("getUser of unknown user got %+v %v", got, err)
+	}
+
+	u := schema.User{Id: 42, UserName: "alice", ChatId: 420, Status: schema.UserStatusRequested}
+	err = s.AddUser(u)
+	if err != nil {
+		t.Fatalf("addUser: %v", err)
+	}
+	err = s.AddUser(u)
+	if err == nil {
+		t.Fatalf("addUser of the same id must fail")
+	}
+	err = s.AddUser(schema.User{Id: 7, Role: "guest", Status: schema.UserStatusActive})
+	if err != nil {
+		t.Fatalf("addUser: %v", err)
+	}
+
+	u.Role = "family"
+	u.Status = schema.UserStatusActive
+	err = s.UpdateUser(u)
+	if err != nil {
+		t.Fatalf("updateUser: %v", err)
+	}
+	got, err = s.GetUser(42)
+	if err != nil {
+		t.Fatalf("getUser: %v", err)
+	}
+	if got.UserName != "alice" || got.ChatId != 420 || got.Role != "family" || got.Status != schema.UserStatusActive || got.CreatedAt.IsZero() {
+		t.Fatalf("getUser got %+v", got)
+	}
+
+	users, err := s.ListUsers()
+	if err != nil {
+		t.Fatalf("listUsers: %v", err)
+	}
+	if len(users) != 2 || users[0].Id != 7 || users[1].Id != 42 {
+		t.Fatalf("listUsers got %+v", users)
+	}
+}
+
+func testUserSettings(t *testing.T, s storage.Storage) {
+	got, err := s.ListUserSettings(42)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("listUserSettings of a new user got %v %v", got, err)
+	}
+
+	for _, kv := range [][2]string{{"timezone", "UTC"}, {"language", "en"}, {"timezone", "Europe/Moscow"}} {
+		err = s.SetUserSetting(42, kv[0], kv[1])
+		if err != nil {
+			t.Fatalf("setUserSetting %s: %v", kv[0], err)
+		}
+	}
+	err = s.SetUserSetting(7, "language", "en")
+	if err != nil {
+		t.Fatalf("setUserSetting: %v", err)
+	}
+
+	got, err = s.ListUserSettings(42)
+	if err != nil || len(got) != 2 || got["timezone"] != "Europe/Moscow" || got["language"] != "en" {
+		t.Fatalf("listUserSettings got %v %v", got, err)
+	}
+
+	err = s.SetUserSetting(42, "language", "")
+	if err != nil {
+		t.Fatalf("setUserSetting to empty: %v", err)
+	}
+	got, err = s.ListUserSettings(42)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("empty value must delete the setting, got %v %v", got, err)
+	}
+
+	all, err := s.ListAllUserSettings()
+	if err != nil {
+		t.Fatalf("listAllUserSettings: %v", err)
+	}
+	if len(all) != 2 || all[0].UserId != 7 || all[1].UserId != 42 || all[1].Value != "Europe/Moscow" {
+		t.Fatalf("listAllUserSettings got %+v", all)
+	}
+}
+
+func testUserAliases(t *testing.T, s storage.Storage) {
+	got, err := s.ListUserAliases(42)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("listUserAliases of a new user got %v %v", got, err)
+	}
+
+	for _, kv := range [][2]string{{"gym", "/note 5bx $*"}, {"w", "/note weight"}, {"gym", "/note 5bx gym $*"}} {
+		err = s.SetUserAlias(42, kv[0], kv[1])
+		if err != nil {
+			t.Fatalf("setUserAlias %s: %v", kv[0], err)
+		}
+	}
+	err = s.SetUserAlias(7, "w", "/note weight")
+	if err != nil {
+		t.Fatalf("setUserAlias: %v", err)
+	}
+
+	got, err = s.ListUserAliases(42)
+	if err != nil || len(got) != 2 || got["gym"] != "/note 5bx gym $*" || got["w"] != "/note weight" {
+		t.Fatalf("listUserAliases got %v %v", got, err)
+	}
+
+	err = s.SetUserAlias(42, "w", "")
+	if err != nil {
+		t.Fatalf("setUserAlias to empty: %v", err)
+	}
+	got, err = s.ListUserAliases(42)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("empty expansion must delete the alias, got %v %v", got, err)
+	}
+
+	all, err := s.ListAllUserAliases()
+	if err != nil {
+		t.Fatalf("listAllUserAliases: %v", err)
+	}
+	if len(all) != 2 || all[0].UserId != 7 || all[1].UserId != 42 || all[1].Expansion != "/note 5bx gym $*" {
+		t.Fatalf("listAllUserAliases got %+v", all)
+	}
+}
+
+func testDeleteAll(t *testing.T, s storage.Storage) {
+	taskId := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+	dialogId, err := s.AddDialog(newDialog())
+	if err != nil {
+		t.Fatalf("addDialog: %v", err)
+	}
+
+	err = s.DeleteAllTasks()
+	if err != nil {
+		t.Fatalf("deleteAllTasks: %v", err)
+	}
+	err = s.DeleteAllDialogs()
+	if err != nil {
+		t.Fatalf("deleteAllDialogs: %v", err)
+	}
+
+	task, err := s.GetTaskById(taskId)
+	if err != nil || task.Id != 0 {
+		t.Fatalf("task left after deleteAll: %+v %v", task, err)
+	}
+	_, err = s.GetDialogById(dialogId)
+	if err == nil {
+		t.Fatalf("dialog left after deleteAll")
+	}
+}
+
+func testListTasks(t *testing.T, s storage.Storage) {
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, mustAddTask(t, s, newTask(1, schema.TaskTypeNote)))
+	}
+
+	page, err := s.ListTasks(0, 2)
+	if err != nil {
+		t.Fatalf("listTasks: %v", err)
+	}
+	if len(page) != 2 || page[0].Id != ids[0] || page[1].Id != ids[1] {
+		t.Fatalf("listTasks first page got %+v", page)
+	}
+	page, err = s.ListTasks(ids[1], 10)
+	if err != nil {
+		t.Fatalf("listTasks: %v", err)
+	}
+	if len(page) != 3 || page[0].Id != ids[2] {
+		t.Fatalf("listTasks second page got %+v", page)
+	}
+}
+
+func testRestoreAndContinue(t *testing.T, s storage.Storage) {
+	created := time.UnixMilli(1700000000000).UTC()
+	d := newDialog()
+	d.Id = 7
+	d.CreatedAt, d.UpdatedAt = created, created
+	err := s.RestoreDialog(d)
+	if err != nil {
+		t.Fatalf("restoreDialog: %v", err)
+	}
+	task := newTask(7, schema.TaskTypeNote)
+	task.Id = 42
+	task.CreatedAt, task.UpdatedAt = created, created
+	err = s.RestoreTask(task)
+	if err != nil {
+		t.Fatalf("restoreTask: %v", err)
+	}
+
+	got, err := s.GetTaskById(42)
+	if err != nil {
+		t.Fatalf("getTaskById: %v", err)
+	}
+	if got.DialogId != 7 || !got.CreatedAt.Equal(created) {
+		t.Fatalf("restored task got %+v", got)
+	}
+	dd, err := s.ListDialogs(0, 10)
+	if err != nil {
+		t.Fatalf("listDialogs: %v", err)
+	}
+	if len(dd) != 1 || dd[0].Id != 7 || !dd[0].CreatedAt.Equal(created) {
+		t.Fatalf("restored dialogs got %+v", dd)
+	}
+
+	// new rows go after the restored ones
+	id := mustAddTask(t, s, newTask(7, schema.TaskTypeNote))
+	if id <= 42 {
+		t.Fatalf("task id after restore is %d", id)
+	}
+	err = s.RestoreTask(task)
+	if err == nil {
+		t.Fatalf("restoreTask of an existing id must fail")
+	}
+}
+
+func testTaskEvents(t *testing.T, s storage.Storage) {
+	old := time.Now().Add(-time.Hour)
+	events := []schema.TaskEvent{
+		{TaskId: 1, TaskType: schema.TaskTypeNote, Command: "addInbox", Kind: schema.TaskEventCreated, CreatedAt: old},
+		{TaskId: 1, TaskType: schema.TaskTypeNote, Command: "addInbox", Kind: schema.TaskEventClaimed, Worker: "notes"},
+		{TaskId: 1, TaskType: schema.TaskTypeNote, Command: "addInbox", Kind: schema.TaskEventDone, Worker: "notes", Message: "ok"},
+	}
+	for _, e := range events {
+		err := s.AddTaskEvent(e)
+		if err != nil {
+			t.Fatalf("addTaskEvent: %v", err)
+		}
+	}
+
+	got, err := s.ListTaskEvents(time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("listTaskEvents: %v", err)
+	}
+	if len(got) != 2 || got[0].Kind != schema.TaskEventClaimed || got[1].Kind != schema.TaskEventDone {
+		t.Fatalf("listTaskEvents got %+v", got)
+	}
+	if got[1].Worker != "notes" || got[1].Message != "ok" || got[1].Command != "addInbox" || got[1].CreatedAt.IsZero() {
+		t.Fatalf("event fields not saved: %+v", got[1])
+	}
+
+	err = s.DeleteAllTasks()
+	if err != nil {
+		t.Fatalf("deleteAllTasks: %v", err)
+	}
+	got, err = s.ListTaskEvents(old)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("events left after deleteAllTasks: %+v %v", got, err)
+	}
+}
+
+func testListTasksByStatus(t *testing.T, s storage.Storage) {
+	first := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+	done := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+	last := mustAddTask(t, s, newTask(1, schema.TaskTypeYtdl))
+	err := s.UpdateTaskStatus(schema.Task{Id: done, Status: schema.TaskStatusDone})
+	if err != nil {
+		t.Fatalf("updateTaskStatus: %v", err)
+	}
+
+	got, err := s.ListTasksByStatus(schema.TaskStatusCreate)
+	if err != nil {
+		t.Fatalf("listTasksByStatus: %v", err)
+	}
+	if len(got) != 2 || got[0].Id != first || got[1].Id != last {
+		t.Fatalf("listTasksByStatus got %+v", got)
+	}
+}
+
+func testUserDialogsAndTasks(t *testing.T, s storage.Storage) {
+	userDialog := func(userId int64) int64 {
+		d := newDialog()
+		d.Messages[0].UserId = userId
+		id, err := s.AddDialog(d)
+		if err != nil {
+			t.Fatalf("addDialog: %v", err)
+		}
+		return id
+	}
+	first, other, last := userDialog(7), userDialog(8), userDialog(7)
+
+	got, err := s.ListUserDialogs(7, 10)
+	if err != nil {
+		t.Fatalf("listUserDialogs: %v", err)
+	}
+	if len(got) != 2 || got[0].Id != last || got[1].Id != first {
+		t.Fatalf("listUserDialogs got %+v", got)
+	}
+	got, err = s.ListUserDialogs(7, 1)
+	if err != nil || len(got) != 1 || got[0].Id != last {
+		t.Fatalf("listUserDialogs with limit got %+v, %v", got, err)
+	}
+
+	open := mustAddTask(t, s, newTask(first, schema.TaskTypeNote))
+	done := mustAddTask(t, s, newTask(first, schema.TaskTypeNote))
+	mustAddTask(t, s, newTask(other, schema.TaskTypeNote))
+	err = s.UpdateTaskStatus(schema.Task{Id: done, Status: schema.TaskStatusDone})
+	if err != nil {
+		t.Fatalf("updateTaskStatus: %v", err)
+	}
+
+	tasks, err := s.ListUserTasks(7, schema.TaskStatusCreate)
+	if err != nil {
+		t.Fatalf("listUserTasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Id != open {
+		t.Fatalf("listUserTasks got %+v", tasks)
+	}
+	all, mine, err := s.CountOpenTasks(schema.TaskTypeNote, 7)
+	if err != nil || all != 2 || mine != 1 {
+		t.Fatalf("countOpenTasks got %d, %d, %v, want 2, 1", all, mine, err)
+	}
+	all, _, err = s.CountOpenTasks(schema.TaskTypeYtdl, 7)
+	if err != nil || all != 0 {
+		t.Fatalf("countOpenTasks of ytdl got %d, %v", all, err)
+	}
+	tasks, err = s.ListDialogTasks(first)
+	if err != nil {
+		t.Fatalf("listDialogTasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Id != open || tasks[1].Id != done {
+		t.Fatalf("listDialogTasks got %+v", tasks)
+	}
+}
+
+func testTaskAlerts(t *testing.T, s storage.Storage) {
+	err := s.AddTaskAlert(schema.TaskAlert{TaskId: 1, Status: schema.TaskStatusCreate})
+	if err != nil {
+		t.Fatalf("addTaskAlert: %v", err)
+	}
+	err = s.AddTaskAlert(schema.TaskAlert{TaskId: 1, Status: schema.TaskStatusSended})
+	if err != nil {
+		t.Fatalf("addTaskAlert other status: %v", err)
+	}
+	err = s.AddTaskAlert(schema.TaskAlert{TaskId: 1, Status: schema.TaskStatusCreate})
+	if err == nil {
+		t.Fatalf("addTaskAlert twice must fail")
+	}
+
+	err = s.DeleteTaskAlert(1, schema.TaskStatusCreate)
+	if err != nil {
+		t.Fatalf("deleteTaskAlert: %v", err)
+	}
+	got, err := s.ListTaskAlerts()
+	if err != nil {
+		t.Fatalf("listTaskAlerts: %v", err)
+	}
+	if len(got) != 1 || got[0].Status != schema.TaskStatusSended || got[0].CreatedAt.IsZero() {
+		t.Fatalf("listTaskAlerts got %+v", got)
+	}
+}
+
+func testInTxCommit(t *testing.T, s storage.Storage) {
+	var taskId, dialogId int64
+	err := s.InTx(func(tx storage.Repo) error {
+		var err error
+		dialogId, err = tx.AddDialog(newDialog())
+		if err != nil {
+			return err
+		}
+		taskId, err = tx.AddTask(newTask(dialogId, schema.TaskTypeNote))
+		if err != nil {
+			return err
+		}
+		// reads inside the transaction see its writes
+		task, err := tx.GetTaskById(taskId)
+		if err != nil {
+			return err
+		}
+		if task.Id != taskId {
+			return errors.New("task is not visible inside the transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("inTx: %v", err)
+	}
+
+	task, err := s.GetTaskById(taskId)
+	if err != nil || task.DialogId != dialogId {
+		t.Fatalf("committed task %+v %v", task, err)
+	}
+}
+
+func testInTxRollback(t *testing.T, s storage.Storage) {
+	keepId := mustAddTask(t, s, newTask(1, schema.TaskTypeNote))
+	fail := errors.New("fail")
+
+	var taskId, dialogId int64
+	err := s.InTx(func(tx storage.Repo) error {
+		var err error
+		dialogId, err = tx.AddDialog(newDialog())
+		if err != nil {
+			return err
+		}
+		taskId, err = tx.AddTask(newTask(dialogId, schema.TaskTypeNote))
+		if err != nil {
+			return err
+		}
+		err = tx.UpdateTaskStatus(schema.Task{Id: keepId, Status: schema.TaskStatusDone})
+		if err != nil {
+			return err
+		}
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Fatalf("inTx must return the error of fn, got %v", err)
+	}
+
+	task, err := s.GetTaskById(taskId)
+	if err != nil || task.Id != 0 {
+		t.Fatalf("task left after rollback: %+v %v", task, err)
+	}
+	_, err = s.GetDialogById(dialogId)
+	if err == nil {
+		t.Fatalf("dialog left after rollback")
+	}
+	kept, err := s.GetTaskById(keepId)
+	if err != nil || kept.Status != schema.TaskStatusCreate {
+		t.Fatalf("update not rolled back: %+v %v", kept, err)
+	}
+}
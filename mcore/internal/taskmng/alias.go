@@ -0,0 +1,174 @@
+package taskmng
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const (
+	// maxAliasDepth limits aliases that expand into other aliases
+	maxAliasDepth = 5
+	maxAliasName  = 32
+)
+
+// expandAliases replaces the first word of text while it is an alias of the
+// user. $1..$9 in the expansion take the words after the alias, $* takes
+// all of them, an expansion without placeholders gets them appended.
+func expandAliases(text string, aliases schema.Aliases) (string, error) {
+	var chain []string
+	for {
+		l := newLexer(text)
+		word, ok, err := l.next()
+		if err != nil || !ok {
+			return text, nil
+		}
+		name := normalize(word)
+		expansion, ok := aliases[name]
+		if !ok {
+			return text, nil
+		}
+		chain = append(chain, name)
+		if slices.Contains(chain[:len(chain)-1], name) {
+			return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasCycle, strings.Join(chain, " > "))
+		}
+		if len(chain) > maxAliasDepth {
+			return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasTooDeep, maxAliasDepth, strings.Join(chain, " > "))
+		}
+		text = substitute(expansion, l.rest())
+	}
+}
+
+// substitute puts args into the placeholders of expansion.
+func substitute(expansion, args string) string {
+	var words []string
+	l := newLexer(args)
+	for {
+		word, ok, err := l.next()
+		if err != nil || !ok {
+			break
+		}
+		words = append(words, word)
+	}
+
+	var b strings.Builder
+	placeholders := false
+	for i := 0; i < len(expansion); i++ {
+		if expansion[i] != '$' || i+1 == len(expansion) {
+			b.WriteByte(expansion[i])
+			continue
+		}
+		next := expansion[i+1]
+		switch {
+		case next == '*':
+			b.WriteString(args)
+		case next >= '1' && next <= '9':
+			if n := int(next - '1'); n < len(words) {
+				b.WriteString(words[n])
+			}
+		default:
+			b.WriteByte('$')
+			continue
+		}
+		placeholders = true
+		i++
+	}
+	if !placeholders && args != "" {
+		b.WriteString(" " + args)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (m *Mng) userAliases(userId int64) (schema.Aliases, error) {
+	if userId == 0 {
+		return schema.Aliases{}, nil
+	}
+	a, err := m.repo.ListUserAliases(userId)
+	if err != nil {
+		return nil, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng aliases of %d: %w", userId, err)
+	}
+	return a, nil
+}
+
+func (m *Mng) listAliases(c call) (string, error) {
+	aliases, err := m.userAliases(c.userId)
+	if err != nil {
+		return "", err
+	}
+	if len(aliases) == 0 {
+		return c.t(i18n.AliasesNone), nil
+	}
+	var b strings.Builder
+	b.WriteString(c.t(i18n.AliasesTitle))
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		fmt.Fprintf(&b, "\n- %s = %s", name, aliases[name])
+	}
+	return b.String(), nil
+}
+
+// addAlias saves the alias, a command, its alias or a shortcut can not be
+// taken and the aliases of the user must still expand.
+func (m *Mng) addAlias(c call) (string, error) {
+	if c.userId == 0 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasesNeedId)
+	}
+	name := normalize(c.arg("name"))
+	if !validAliasName(name) {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasBadName, c.arg("name"), maxAliasName)
+	}
+	if m.commands.known(name) {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasTaken, name)
+	}
+	aliases, err := m.userAliases(c.userId)
+	if err != nil {
+		return "", err
+	}
+	expansion := c.arg("expansion")
+	aliases[name] = expansion
+	_, err = expandAliases(name, aliases)
+	if err != nil {
+		return "", err
+	}
+
+	err = m.repo.SetUserAlias(c.userId, name, expansion)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng add alias: %w", err)
+	}
+	return c.t(i18n.AliasSaved, name, expansion), nil
+}
+
+func (m *Mng) delAlias(c call) (string, error) {
+	if c.userId == 0 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasesNeedId)
+	}
+	name := normalize(c.arg("name"))
+	aliases, err := m.userAliases(c.userId)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := aliases[name]; !ok {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.AliasNotFound, name)
+	}
+	err = m.repo.SetUserAlias(c.userId, name, "")
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng del alias: %w", err)
+	}
+	return c.t(i18n.AliasDeleted, name), nil
+}
+
+func validAliasName(name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > maxAliasName {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !('a' <= r && r <= 'z') && !('0' <= r && r <= '9') && !('а' <= r && r <= 'я') && r != 'ё' {
+			return false
+		}
+	}
+	return true
+}
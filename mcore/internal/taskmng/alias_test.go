@@ -0,0 +1,125 @@
+package taskmng
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestExpandAliases(t *testing.T) {
+	aliases := schema.Aliases{
+		"buy":   "/note inbox add buy",
+		"movie": "/ds add movie $1",
+		"swap":  "/note inbox add $2 $1",
+		"all":   "/note inbox add [$*]",
+		"cost":  "/note inbox add $5 costs $$1",
+		"m":     "movie",
+		"loop":  "pool",
+		"pool":  "loop",
+		"a1":    "a2",
+		"a2":    "a3",
+		"a3":    "a4",
+		"a4":    "a5",
+		"a5":    "a6",
+		"a6":    "/ping",
+	}
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"no alias", "/ping", "/ping", false},
+		{"appended args", "buy milk  and bread", "/note inbox add buy milk  and bread", false},
+		{"no args", "buy", "/note inbox add buy", false},
+		{"slash and case", "/BUY milk", "/note inbox add buy milk", false},
+		{"placeholder", "movie magnet:x", "/ds add movie magnet:x", false},
+		{"placeholder without arg", "movie", "/ds add movie", false},
+		{"extra args dropped", "movie a b", "/ds add movie a", false},
+		{"order", "swap a b", "/note inbox add b a", false},
+		{"quoted word", `swap "a b" c`, "/note inbox add c a b", false},
+		{"all args raw", `all it's "x"`, `/note inbox add [it's "x"]`, false},
+		{"dollar kept", "cost 5", "/note inbox add  costs $5", false},
+		{"chain", "m x", "/ds add movie x", false},
+		{"open quote in args", `buy "milk`, `/note inbox add buy "milk`, false},
+		{"open quote first", `"buy milk`, `"buy milk`, false},
+		{"cycle", "loop", "", true},
+		{"too deep", "a1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandAliases(tt.text, aliases)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expandAliases(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidAliasName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"buy", true},
+		{"buy_2", true},
+		{"купить", true},
+		{"ёлка", true},
+		{"", false},
+		{"Buy", false},
+		{"buy-it", false},
+		{"a b", false},
+		{"abcdefghijklmnopqrstuvwxyz012345", true},
+		{"abcdefghijklmnopqrstuvwxyz0123456", false},
+	}
+	for _, tt := range tests {
+		if got := validAliasName(tt.name); got != tt.want {
+			t.Errorf("validAliasName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddAlias(t *testing.T) {
+	m := newTestMng(t, Config{})
+	err := m.repo.SetUserAlias(1, "loop", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		userId    int64
+		alias     string
+		expansion string
+		wantErr   bool
+	}{
+		{"ok", 1, "Buy", "/note inbox add buy", false},
+		{"no user id", 0, "buy", "/ping", true},
+		{"bad name", 1, "b-y", "/ping", true},
+		{"command", 1, "ping", "/help", true},
+		{"command alias", 1, "t", "/help", true},
+		{"shortcut", 1, "dsl", "/help", true},
+		{"cycle", 1, "pool", "loop", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCall(tt.userId, nil)
+			c.args["name"] = tt.alias
+			c.args["expansion"] = tt.expansion
+			_, err := m.addAlias(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	aliases, err := m.repo.ListUserAliases(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 2 || aliases["buy"] != "/note inbox add buy" {
+		t.Errorf("aliases = %v, want loop and buy", aliases)
+	}
+}
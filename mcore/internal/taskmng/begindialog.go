@@ -1,92 +1,153 @@
 package taskmng
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
-const trHelpText = ` This is help for /torrent command
-start words: /torrent, t, T
-next words:
-- "add" + category: "movie/m","shows/s", "cartoon/c", "cartoon_s/cs", "audiobook/a", "audiobook_p/ap" - add torrents in category
-- "list" - list torrens in actions
-- "del #" - where # it is id torrent
-`
-
-const tnHelpText = `This is help for /note commands:
-- /note 5bx - add 5bx line. Synonyms: n5/N5
-- /note entry - add message to entry. Synonyms: nd/Nd
-- /note weight - add weight. Synonyms: nw/Nw
-- /note bp - add blood pressure. Synonyms: nbp/Nbp
-- /note inbox add - add text to inbox. Synonyms: ni/Ni
-- /note inbox read - read inbox. Synonyms: nir/Nir
-- /note pull - just update repo
-`
-
-const helpCommon = ` My commands:
-- /help
-- /ping
-- /y2d
-- /torrent
-- /finance
-- /ds
-`
-
-func (m *Mng) ProcessDialogBegin(dialogId int64) (string, error) {
+func (m *Mng) ProcessDialogBegin(dialogId int64) (schema.TaskMsg, error) {
 	dialog, err := m.repo.GetDialogById(dialogId)
 	if err != nil {
-		return "", fmt.Errorf("taskMng get dialog by id: %w", err)
+		return schema.TaskMsg{}, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng get dialog by id: %w", err)
 	}
 	if dialog.DialogStatus != schema.DialogStatusBegin {
-		return "", fmt.Errorf("wrong dialog status")
+		return schema.TaskMsg{}, fmt.Errorf("wrong dialog status")
 	}
 
 	if len(dialog.Messages) > 1 {
-		return "", fmt.Errorf("wrong number of messages")
+		return schema.TaskMsg{}, fmt.Errorf("wrong number of messages")
 	}
 
-	userText := dialog.Messages[0].Text
+	first := dialog.Messages[0]
+	userText := first.Text
 	if len(userText) == 0 {
-		userText = dialog.Messages[0].Caption
-		if len(userText) == 0 {
-			return "", fmt.Errorf("dialog text and captions is empty")
+		userText = first.Caption
+	}
+	// a file alone may still be a torrent
+	if len(userText) == 0 && first.FileUrl == "" {
+		return schema.TaskMsg{}, schema.Localized(schema.ErrCodeInvalidArgument, i18n.MessageEmpty)
+	}
+
+	user, err := m.userCall(first)
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	aliases, err := m.userAliases(first.UserId)
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	userText, err = expandAliases(userText, aliases)
+	if err != nil {
+		return schema.TaskMsg{}, m.failDialog(dialogId, err, errorReply(err, user.lang))
+	}
+
+	var reply schema.TaskMsg
+	err = m.inTx(func(m *Mng) error {
+		cmd, c, err := m.intentCall(userText, first.FileUrl, user)
+		if cmd == nil {
+			cmd, c, err = m.commands.parse(userText, user)
+		}
+		if cmd == nil {
+			return err
+		}
+		c.dialogId = dialogId
+		c.fileUrl = first.FileUrl
+		// a rollback leaves the dialog as it was
+		d, getErr := m.repo.GetDialogById(dialogId)
+		if getErr != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng get dialog by id: %w", getErr)
+		}
+		reply, err = m.runCommand(&d, cmd, c, err)
+		if err != nil {
+			return err
 		}
+		if warning := m.offlineWarning(*m.added, user.lang); warning != "" {
+			reply.Text += "\n" + warning
+		}
+		return m.record(&d, first.ChatId, reply.Text)
+	})
+	if err != nil {
+		// tasks of the dialog are rolled back
+		if msg, ok := m.suggestMsg(userText, user, err); ok {
+			return msg, m.failDialog(dialogId, nil, msg.Text)
+		}
+		return schema.TaskMsg{}, m.failDialog(dialogId, err, errorReply(err, user.lang))
 	}
+	return reply, nil
+}
 
-	return m.createReply(dialogId, dialog.Messages[0].UserName, userText, dialog.Messages[0].FileUrl)
+// failDialog keeps the dialog as a record of the failed command with text,
+// the answer the user reads. err is returned unless the dialog can not be
+// saved.
+func (m *Mng) failDialog(dialogId int64, err error, text string) error {
+	updErr := m.changeDialog(dialogId, func(dialog *schema.Dialog) {
+		dialog.DialogStatus = schema.DialogStatusError
+		appendBot(dialog, dialog.Messages[0].ChatId, text, 0)
+	})
+	if updErr != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng mark dialog error: %w", updErr)
+	}
+	return err
 }
 
-func (m *Mng) createReply(dialogId int64, userName string, userText string, fileUrl string) (string, error) {
-	s := strings.Split(userText, " ")
-	switch s[0] {
-	case "/help", "h", "H":
-		return helpCommon, nil
-	case "/ping", "ping", "Ping":
-		return "Pong", nil
-	case "/y2d", "y", "Y":
-		return m.createYtdlTask(dialogId, userName, userText)
-	case "/torrent", "torrent", "t", "T":
-		return m.createTrTask(dialogId, userText, fileUrl)
-	case "/note", "n", "nd", "Nd", "n5", "N5", "ni", "Ni", "nir", "Nir", "nw", "Nw", "nbp", "Nbp":
-		return m.createNoteTask(dialogId, userText)
-	case "health", "/health":
-		return m.createHealth(dialogId)
-	case "/finance", "f", "F":
-		return m.createFinanceTask(dialogId, userText)
-	case "/free":
-		return m.createFreeTask(dialogId)
-	case "/ds", "ds", "dsm", "Dsm", "dsc", "Dsc", "dss", "Dss", "dsa", "Dsa", "dso", "Dso", "dscs", "Dscs", "dsl", "Dsl":
-		return m.createSynoTask(dialogId, userText, fileUrl)
+// runCommand runs cmd if the user may, unless parsing already asked for a
+// missing arg. When the command asks, its call is saved in the dialog,
+// which waits for a reply.
+func (m *Mng) runCommand(dialog *schema.Dialog, cmd *command, c call, parseErr error) (schema.TaskMsg, error) {
+	if !m.access.Grants(c.role, cmd.perm) {
+		return schema.TaskMsg{}, schema.Localized(schema.ErrCodeNotPermitted, i18n.NotPermitted, usagePath(c.path), cmd.perm)
+	}
+	err := parseErr
+	if err == nil {
+		var reply string
+		reply, err = cmd.run(m, c)
+		if err == nil {
+			return schema.TaskMsg{Text: reply}, nil
+		}
+	}
+	var r *redirect
+	if errors.As(err, &r) {
+		next := m.commands.lookup(r.path)
+		if next == nil || next.run == nil {
+			return schema.TaskMsg{}, fmt.Errorf("redirect to unknown command %v", r.path)
+		}
+		c.path, c.args = r.path, r.args
+		return m.runCommand(dialog, next, c, parseArgs(newLexer(""), next, c, 0))
+	}
+	var a *ask
+	if !errors.As(err, &a) {
+		return schema.TaskMsg{}, err
+	}
+
+	dialog.DialogStatus = schema.DialogStatusWaitReply
+	dialog.State = schema.DialogState{
+		Path:     c.path,
+		Args:     c.args,
+		FileUrl:  c.fileUrl,
+		Ask:      a.arg,
+		Question: a.question,
+		Buttons:  a.buttons,
 	}
+	err = m.repo.UpdateDialog(*dialog)
+	if err != nil {
+		return schema.TaskMsg{}, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng save dialog state: %w", err)
+	}
+	return askMsg(c.lang, a.question, a.buttons), nil
+}
 
-	return "", fmt.Errorf("command not found")
+func askMsg(lang i18n.Lang, question string, buttons [][]schema.Button) schema.TaskMsg {
+	if len(buttons) > 0 {
+		return schema.TaskMsg{Text: question, Buttons: buttons}
+	}
+	return schema.TaskMsg{Text: question + "\n" + i18n.T(lang, i18n.ReplyHint)}
 }
 
-func (m *Mng) createHealth(dialogId int64) (string, error) {
+func (m *Mng) createHealth(c call) (string, error) {
 	taskTemolata := schema.Task{
-		DialogId: dialogId,
+		DialogId: c.dialogId,
 		Status:   schema.TaskStatusCreate,
 		TaskData: schema.TaskData{
 			Health: "health",
@@ -101,11 +162,11 @@ func (m *Mng) createHealth(dialogId int64) (string, error) {
 
 	for _, taskType := range taskTypes {
 		taskTemolata.Type = taskType
-		_, err := m.repo.AddTask(taskTemolata)
+		_, err := m.addTask(taskTemolata)
 		if err != nil {
-			return "", fmt.Errorf("health type=%d: %w", taskTypes, err)
+			return "", schema.Errorf(schema.ErrCodeStorageFailure, "health type=%d: %w", taskType, err)
 		}
 	}
-	return "tasks health created", nil
+	return c.t(i18n.HealthCreated), nil
 
 }
@@ -0,0 +1,454 @@
+package taskmng
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+type argKind int
+
+const (
+	argWord argKind = iota // one token
+	argInt                 // one token, a number
+	argRest                // raw rest of the message, newlines kept
+)
+
+type arg struct {
+	name     string
+	kind     argKind
+	optional bool
+	// prompt is asked when a required arg is missing, the reply to it
+	// continues the command, catalogs translate it by i18n.Prompt
+	prompt string
+	// choices are offered as buttons with the prompt
+	choices []string
+	// setting gives the value of a missing arg when the user has set it
+	setting string
+}
+
+// ask is returned instead of an answer when the command waits for the user
+// to reply with the value of arg.
+type ask struct {
+	arg      string
+	question string
+	buttons  [][]schema.Button
+}
+
+func (a *ask) Error() string {
+	return a.question
+}
+
+// redirect is returned by a command that hands the call over to the
+// command at path with args.
+type redirect struct {
+	path []string
+	args map[string]string
+}
+
+func (r *redirect) Error() string {
+	return "redirect to " + usagePath(r.path)
+}
+
+type handler func(m *Mng, c call) (string, error)
+
+// command is a node of the command tree, either it has subs or it runs.
+type command struct {
+	name    string
+	aliases []string
+	// summary is in english, catalogs translate it by i18n.Summary
+	summary string
+	perm    access.Perm
+	args    []arg
+	subs    []*command
+	run     handler
+	// hidden commands work but are not listed in help
+	hidden bool
+}
+
+// shortcut is a word that stands for the beginning of a longer command,
+// e.g. ni for /note inbox add.
+type shortcut struct {
+	name    string
+	expands string
+}
+
+// call is one parsed message.
+type call struct {
+	dialogId int64
+	userId   int64
+	userName string
+	// role, settings and language of the user when the message came
+	role     string
+	settings schema.Settings
+	lang     i18n.Lang
+	fileUrl  string
+	// path holds names of the commands from the top, e.g. note inbox add
+	path []string
+	args map[string]string
+}
+
+// t renders a text in the language of the user.
+func (c call) t(key i18n.Key, args ...any) string {
+	return i18n.T(c.lang, key, args...)
+}
+
+func (c call) arg(name string) string {
+	return c.args[name]
+}
+
+// intArg is only called for argInt args, which parse has checked.
+func (c call) intArg(name string) int {
+	n, _ := strconv.Atoi(c.args[name])
+	return n
+}
+
+type registry struct {
+	commands  []*command
+	shortcuts []shortcut
+}
+
+func normalize(word string) string {
+	return strings.ToLower(strings.TrimPrefix(word, "/"))
+}
+
+func (cmd *command) matches(word string) bool {
+	w := normalize(word)
+	if w == cmd.name {
+		return true
+	}
+	for _, a := range cmd.aliases {
+		if w == a {
+			return true
+		}
+	}
+	return false
+}
+
+func findCommand(cmds []*command, word string) *command {
+	for _, cmd := range cmds {
+		if cmd.matches(word) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// known reports whether word is a command, its alias or a shortcut.
+func (r *registry) known(word string) bool {
+	if findCommand(r.commands, word) != nil {
+		return true
+	}
+	for _, s := range r.shortcuts {
+		if strings.EqualFold(normalize(word), s.name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *registry) expand(text string) string {
+	l := newLexer(text)
+	first, ok, err := l.next()
+	if err != nil || !ok {
+		return text
+	}
+	for _, s := range r.shortcuts {
+		if strings.EqualFold(first, s.name) {
+			return s.expands + " " + l.text[l.pos:]
+		}
+	}
+	return text
+}
+
+// parse finds the command of the message and its args, user is the call
+// with the details of the sender. A "help" word in
+// place of a sub command answers with the help of the parent. When a
+// required arg with a prompt is missing, the command and the call come
+// back together with an *ask.
+func (r *registry) parse(text string, user call) (*command, call, error) {
+	c := user
+	l := newLexer(r.expand(text))
+
+	word, ok, err := l.next()
+	if err != nil {
+		return nil, c, err
+	}
+	if !ok {
+		return nil, c, schema.Localized(schema.ErrCodeInvalidArgument, i18n.MessageEmpty)
+	}
+	cmd := findCommand(r.commands, word)
+	if cmd == nil {
+		return nil, c, schema.Localized(schema.ErrCodeUnknownCommand, i18n.CommandNotFound, word)
+	}
+	c.path = append(c.path, cmd.name)
+
+	for len(cmd.subs) > 0 {
+		word, ok, err = l.next()
+		if err != nil {
+			return nil, c, err
+		}
+		if !ok {
+			help, _ := r.commandHelp(c.path, nil, c.lang)
+			return nil, c, schema.Localized(schema.ErrCodeInvalidArgument, i18n.NeedSubcommand, usagePath(c.path), help)
+		}
+		if normalize(word) == "help" {
+			c.args = map[string]string{"command": strings.Join(c.path, " ")}
+			c.path = []string{"help"}
+			return r.helpCommand(), c, nil
+		}
+		sub := findCommand(cmd.subs, word)
+		if sub == nil {
+			return nil, c, schema.Localized(schema.ErrCodeUnknownCommand, i18n.UnknownSub, word, usagePath(c.path))
+		}
+		cmd = sub
+		c.path = append(c.path, cmd.name)
+	}
+
+	err = parseArgs(l, cmd, c, 0)
+	return cmd, c, err
+}
+
+// parseArgs reads args of cmd from l starting with the arg at from,
+// args the call already has are skipped.
+func parseArgs(l *lexer, cmd *command, c call, from int) error {
+	var missing *ask
+	for _, a := range cmd.args[from:] {
+		if c.args[a.name] != "" {
+			continue
+		}
+		if a.kind == argRest {
+			c.args[a.name] = l.rest()
+		} else {
+			word, ok, err := l.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				c.args[a.name] = word
+			}
+		}
+		if c.args[a.name] == "" {
+			if a.optional {
+				continue
+			}
+			if v := c.settings[a.setting]; a.setting != "" && v != "" {
+				c.args[a.name] = v
+				continue
+			}
+			if a.prompt == "" {
+				return schema.Localized(schema.ErrCodeInvalidArgument, i18n.NeedArg, usagePath(c.path), a.name, usage(c.path, cmd))
+			}
+			if missing == nil {
+				question := i18n.Or(c.lang, i18n.Prompt(strings.Join(c.path, " "), a.name), a.prompt)
+				missing = &ask{arg: a.name, question: question, buttons: choiceButtons(a.choices...)}
+			}
+			continue
+		}
+		if a.kind == argInt {
+			_, err := strconv.Atoi(c.args[a.name])
+			if err != nil {
+				return schema.Localized(schema.ErrCodeInvalidArgument, i18n.NotNumber, a.name, usagePath(c.path))
+			}
+		}
+	}
+	if extra := l.rest(); extra != "" {
+		return schema.Localized(schema.ErrCodeInvalidArgument, i18n.TooManyArgs, usage(c.path, cmd))
+	}
+	if missing != nil {
+		return missing
+	}
+	return nil
+}
+
+// lookup returns the command at path of names, nil if there is none.
+func (r *registry) lookup(path []string) *command {
+	var cmd *command
+	cmds := r.commands
+	for _, name := range path {
+		cmd = findCommand(cmds, name)
+		if cmd == nil {
+			return nil
+		}
+		cmds = cmd.subs
+	}
+	return cmd
+}
+
+// choiceButtons lays out buttons that answer with their text,
+// three in a row.
+func choiceButtons(choices ...string) [][]schema.Button {
+	var rows [][]schema.Button
+	for i, choice := range choices {
+		if i%3 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], schema.Button{Text: choice, Data: choice})
+	}
+	return rows
+}
+
+// yesNoButtons answer a question with yes or no in any language.
+func yesNoButtons(c call) [][]schema.Button {
+	return [][]schema.Button{{
+		{Text: c.t(i18n.Yes), Data: "yes"},
+		{Text: c.t(i18n.No), Data: "no"},
+	}}
+}
+
+func isYes(answer string) bool {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "yes", "y", "да":
+		return true
+	}
+	return false
+}
+
+func argIndex(cmd *command, name string) int {
+	for i, a := range cmd.args {
+		if a.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func usagePath(path []string) string {
+	return "/" + strings.Join(path, " ")
+}
+
+func usage(path []string, cmd *command) string {
+	var b strings.Builder
+	b.WriteString(usagePath(path))
+	for _, a := range cmd.args {
+		if a.optional {
+			fmt.Fprintf(&b, " [%s]", a.name)
+		} else {
+			fmt.Fprintf(&b, " <%s>", a.name)
+		}
+	}
+	return b.String()
+}
+
+// allowFunc tells which perms the user has, nil allows all.
+type allowFunc func(p access.Perm) bool
+
+// allowed reports whether the user may run cmd or any of its sub commands.
+func (cmd *command) allowed(allow allowFunc) bool {
+	if allow == nil {
+		return true
+	}
+	if len(cmd.subs) == 0 {
+		return allow(cmd.perm)
+	}
+	for _, sub := range cmd.subs {
+		if sub.allowed(allow) {
+			return true
+		}
+	}
+	return false
+}
+
+// setPerm sets the perm of cmd and all its sub commands.
+func (cmd *command) setPerm(p access.Perm) {
+	cmd.perm = p
+	for _, sub := range cmd.subs {
+		sub.setPerm(p)
+	}
+}
+
+func (r *registry) helpCommand() *command {
+	return findCommand(r.commands, "help")
+}
+
+// summaryIn returns the summary of cmd at path in lang.
+func (cmd *command) summaryIn(path []string, lang i18n.Lang) string {
+	return i18n.Or(lang, i18n.Summary(strings.Join(path, " ")), cmd.summary)
+}
+
+// help lists top level commands the user may run.
+func (r *registry) help(allow allowFunc, lang i18n.Lang) string {
+	var b strings.Builder
+	b.WriteString(i18n.T(lang, i18n.HelpTitle) + "\n")
+	for _, cmd := range r.commands {
+		if cmd.hidden || !cmd.allowed(allow) {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s - %s", usage([]string{cmd.name}, cmd), cmd.summaryIn([]string{cmd.name}, lang))
+		if len(cmd.aliases) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(cmd.aliases, ", "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(i18n.T(lang, i18n.HelpMore))
+	return b.String()
+}
+
+// commandHelp describes the command at path with the sub commands the user
+// may run, path may use aliases and shortcuts.
+func (r *registry) commandHelp(path []string, allow allowFunc, lang i18n.Lang) (string, error) {
+	if len(path) == 0 {
+		return r.help(allow, lang), nil
+	}
+	path = strings.Fields(r.expand(strings.Join(path, " ")))
+
+	var names []string
+	var cmd *command
+	cmds := r.commands
+	for _, word := range path {
+		cmd = findCommand(cmds, word)
+		if cmd == nil {
+			return "", schema.Localized(schema.ErrCodeUnknownCommand, i18n.NoHelp, strings.Join(path, " "))
+		}
+		names = append(names, cmd.name)
+		cmds = cmd.subs
+		if len(cmds) == 0 {
+			break
+		}
+	}
+
+	var b strings.Builder
+	if len(cmd.subs) == 0 {
+		fmt.Fprintf(&b, "%s - %s\n", usage(names, cmd), cmd.summaryIn(names, lang))
+	} else {
+		fmt.Fprintf(&b, "%s - %s\n", usagePath(names), cmd.summaryIn(names, lang))
+	}
+	if len(cmd.aliases) > 0 {
+		b.WriteString(i18n.T(lang, i18n.HelpAliases, strings.Join(cmd.aliases, ", ")) + "\n")
+	}
+	writeSubs(&b, names, cmd.subs, allow, lang)
+
+	prefix := usagePath(names)
+	var shortcuts []string
+	for _, s := range r.shortcuts {
+		if strings.HasPrefix(s.expands+" ", prefix+" ") {
+			shortcuts = append(shortcuts, fmt.Sprintf("%s = %s", s.name, s.expands))
+		}
+	}
+	if len(shortcuts) > 0 {
+		b.WriteString(i18n.T(lang, i18n.HelpShortcuts, strings.Join(shortcuts, ", ")) + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func writeSubs(b *strings.Builder, path []string, subs []*command, allow allowFunc, lang i18n.Lang) {
+	for _, sub := range subs {
+		if sub.hidden || !sub.allowed(allow) {
+			continue
+		}
+		subPath := append(append([]string{}, path...), sub.name)
+		if len(sub.subs) > 0 {
+			writeSubs(b, subPath, sub.subs, allow, lang)
+			continue
+		}
+		fmt.Fprintf(b, "- %s - %s", usage(subPath, sub), sub.summaryIn(subPath, lang))
+		if len(sub.aliases) > 0 {
+			fmt.Fprintf(b, " (%s)", strings.Join(sub.aliases, ", "))
+		}
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,187 @@
+package taskmng
+
+import (
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const (
+	trCategories   = "movie/m, shows/s, cartoon/c, cartoon_s/cs, audiobook/a, audiobook_p/ap"
+	synoCategories = "movie/m, cartoon/c, shows/s, audiobook/a, other/o, shows_cartoons/cs"
+)
+
+var (
+	trChoices   = []string{"movie", "shows", "cartoon", "cartoon_s", "audiobook", "audiobook_p"}
+	synoChoices = []string{"movie", "cartoon", "shows", "audiobook", "other", "shows_cartoons"}
+)
+
+func newRegistry() *registry {
+	return &registry{
+		commands: []*command{
+			{
+				name: "help", aliases: []string{"h"}, summary: "list commands", perm: access.PermBasic,
+				args: []arg{{name: "command", kind: argRest, optional: true}},
+				run:  (*Mng).runHelp,
+			},
+			{
+				name: "ping", summary: "check that the bot answers", perm: access.PermBasic,
+				run: func(_ *Mng, _ call) (string, error) { return "Pong", nil },
+			},
+			{
+				name: "y2d", aliases: []string{"y"}, summary: "download a youtube video into the feed", perm: access.PermDownloads,
+				args: []arg{{name: "link", prompt: "send the youtube link"}},
+				run:  (*Mng).createYtdlTask,
+			},
+			{
+				name: "torrent", aliases: []string{"t"}, summary: "torrents in transmission", perm: access.PermDownloads,
+				subs: []*command{
+					{
+						name: "add", summary: "add a torrent link or the attached file, categories: " + trCategories, perm: access.PermDownloads,
+						args: []arg{{name: "category", prompt: "which category? " + trCategories, choices: trChoices}, {name: "url", optional: true}},
+						run:  (*Mng).createTrTask,
+					},
+					{name: "list", summary: "list torrents in action", perm: access.PermDownloads, run: (*Mng).createTrTask},
+					{
+						name: "del", summary: "delete a torrent by id", perm: access.PermDelete,
+						args: []arg{{name: "id", kind: argInt, prompt: "which torrent? /torrent list shows ids"}},
+						run:  (*Mng).createTrTask,
+					},
+				},
+			},
+			{
+				name: "note", aliases: []string{"n"}, summary: "notes in the git repo", perm: access.PermNotes,
+				subs: []*command{
+					{name: "5bx", summary: "add a 5bx line", perm: access.PermNotes, args: noteText, run: noteTask(schema.TaskNoteCmdAdd5bx)},
+					{name: "entry", summary: "add the message to the entry", perm: access.PermNotes, args: noteText, run: noteTask(schema.TaskNoteCmdAddEntry)},
+					{name: "weight", summary: "add weight", perm: access.PermNotes, args: noteText, run: noteTask(schema.TaskNoteCmdAddWeight)},
+					{name: "bp", summary: "add blood pressure", perm: access.PermNotes, args: noteText, run: noteTask(schema.TaskNoteCmdAddBP)},
+					{
+						name: "inbox", summary: "inbox of the notes", perm: access.PermInbox,
+						subs: []*command{
+							{name: "add", summary: "add text to the inbox", perm: access.PermInbox, args: noteText, run: noteTask(schema.TaskNoteCmdAddInbox)},
+							{name: "read", summary: "read the inbox", perm: access.PermInbox, run: noteTask(schema.TaskNoteReadInbox)},
+						},
+					},
+					{name: "pull", summary: "just update the repo", perm: access.PermNotes, run: noteTask(schema.TaskNoteCmdPull)},
+				},
+			},
+			{
+				name: "finance", aliases: []string{"f"}, summary: "finance reports", perm: access.PermFinance,
+				subs: []*command{
+					{name: "run", aliases: []string{"r"}, summary: "run the report", perm: access.PermFinance, run: (*Mng).createFinanceTask},
+					{name: "load", aliases: []string{"l"}, summary: "load new data", perm: access.PermFinance, run: (*Mng).createFinanceTask},
+					{name: "transactions", aliases: []string{"t"}, summary: "show transactions", perm: access.PermFinance, run: (*Mng).createFinanceTask},
+				},
+			},
+			{
+				name: "ds", summary: "Download Station on synology", perm: access.PermDownloads,
+				subs: []*command{
+					{
+						name: "add", summary: "add a torrent link or the attached file, categories: " + synoCategories, perm: access.PermDownloads,
+						args: []arg{
+							{name: "category", prompt: "which category? " + synoCategories, choices: synoChoices, setting: schema.SettingDownloadCategory},
+							{name: "url", optional: true},
+						},
+						run: (*Mng).createSynoTask,
+					},
+					{name: "list", summary: "show active downloads", perm: access.PermDownloads, run: (*Mng).createSynoTask},
+					{
+						name: "del", aliases: []string{"delete"}, summary: "delete a download by id after a confirmation", perm: access.PermDelete,
+						args: []arg{{name: "id", prompt: "which download? /ds list shows ids"}},
+						run:  (*Mng).createSynoTask,
+					},
+				},
+			},
+			{
+				name: "stats", summary: "task durations and failures", perm: access.PermAdmin,
+				args: []arg{{name: "days", kind: argInt, optional: true}},
+				run:  (*Mng).createStats,
+			},
+			{name: "health", summary: "ask workers for their health", perm: access.PermAdmin, run: (*Mng).createHealth},
+			{name: "status", summary: "your open tasks", perm: access.PermBasic, run: (*Mng).listStatus},
+			{
+				name: "history", summary: "your last dialogs, a number says how many, a command picks its dialogs", perm: access.PermBasic,
+				args: []arg{{name: "filter", kind: argRest, optional: true}},
+				run:  (*Mng).listHistory,
+			},
+			{
+				name: "settings", summary: "your settings, workers use them", perm: access.PermBasic,
+				subs: []*command{
+					{name: "list", summary: "list settings with their values", perm: access.PermBasic, run: (*Mng).listSettings},
+					{
+						name: "get", summary: "show a setting", perm: access.PermBasic,
+						args: []arg{{name: "key", prompt: "which setting?", choices: settingKeys()}},
+						run:  (*Mng).getSetting,
+					},
+					{
+						name: "set", summary: "set a setting, no value clears it", perm: access.PermBasic,
+						args: []arg{{name: "key", prompt: "which setting?", choices: settingKeys()}, {name: "value", kind: argRest, optional: true}},
+						run:  (*Mng).setSetting,
+					},
+				},
+			},
+			{
+				name: "alias", summary: "your aliases of commands", perm: access.PermBasic,
+				subs: []*command{
+					{name: "list", summary: "list aliases", perm: access.PermBasic, run: (*Mng).listAliases},
+					{
+						name: "add", summary: "add an alias, $1..$9 and $* take its args", perm: access.PermBasic,
+						args: []arg{{name: "name"}, {name: "expansion", kind: argRest}},
+						run:  (*Mng).addAlias,
+					},
+					{name: "del", summary: "delete an alias", perm: access.PermBasic, args: []arg{{name: "name"}}, run: (*Mng).delAlias},
+				},
+			},
+			{
+				name: "users", summary: "users of the bot and their roles", perm: access.PermAdmin,
+				subs: []*command{
+					{name: "list", summary: "list users with roles and access requests", perm: access.PermAdmin, run: (*Mng).listUsers},
+					{
+						name: "approve", summary: "approve an access request with a role, guest by default", perm: access.PermAdmin,
+						args: []arg{{name: "id", kind: argInt}, {name: "role", optional: true}},
+						run:  (*Mng).approveUser,
+					},
+					{name: "deny", summary: "deny an access request", perm: access.PermAdmin, args: userIdArg, run: (*Mng).denyUser},
+					{name: "revoke", summary: "take access away from a user", perm: access.PermAdmin, args: userIdArg, run: (*Mng).revokeUser},
+					{
+						name: "role", summary: "set the role of a user, adds the user by telegram id", perm: access.PermAdmin,
+						args: []arg{{name: "id", kind: argInt}, {name: "role"}},
+						run:  (*Mng).setUserRole,
+					},
+				},
+			},
+			{name: "free", summary: "not done yet", perm: access.PermBasic, run: (*Mng).createFreeTask, hidden: true},
+			{name: intentArg, summary: "confirm a command guessed from a link, file or text", perm: access.PermBasic, run: (*Mng).confirmIntent, hidden: true},
+		},
+		shortcuts: []shortcut{
+			{name: "nd", expands: "/note entry"},
+			{name: "n5", expands: "/note 5bx"},
+			{name: "ni", expands: "/note inbox add"},
+			{name: "nir", expands: "/note inbox read"},
+			{name: "nw", expands: "/note weight"},
+			{name: "nbp", expands: "/note bp"},
+			{name: "dsm", expands: "/ds add movie"},
+			{name: "dsc", expands: "/ds add cartoon"},
+			{name: "dss", expands: "/ds add shows"},
+			{name: "dsa", expands: "/ds add audiobook"},
+			{name: "dso", expands: "/ds add other"},
+			{name: "dscs", expands: "/ds add shows_cartoons"},
+			{name: "dsl", expands: "/ds list"},
+		},
+	}
+}
+
+var (
+	noteText  = []arg{{name: "text", kind: argRest, optional: true}}
+	userIdArg = []arg{{name: "id", kind: argInt}}
+)
+
+func (m *Mng) runHelp(c call) (string, error) {
+	allow := func(p access.Perm) bool {
+		return m.access.Grants(c.role, p)
+	}
+	if c.arg("command") == "" {
+		return m.commands.help(allow, c.lang), nil
+	}
+	return m.commands.commandHelp([]string{c.arg("command")}, allow, c.lang)
+}
@@ -0,0 +1,122 @@
+package taskmng
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// polls remembers when workers of each type last asked for a task.
+// It lives in memory, after a restart workers show up with their next poll.
+type polls struct {
+	mu   sync.Mutex
+	last map[schema.TaskType]time.Time
+}
+
+func (p *polls) seen(t schema.TaskType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last[t] = time.Now()
+}
+
+func (p *polls) lastSeen(t schema.TaskType) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last[t]
+}
+
+// offlineWarning names the types of added tasks whose worker has not polled
+// for a while, tbot itself is always online when it brings messages.
+func (m *Mng) offlineWarning(added []schema.TaskType, lang i18n.Lang) string {
+	if m.offlineAfter == 0 {
+		return ""
+	}
+	var offline []string
+	for _, t := range added {
+		if t == schema.TaskTypeMsg || slices.Contains(offline, t.String()) {
+			continue
+		}
+		if time.Since(m.polls.lastSeen(t)) > m.offlineAfter {
+			offline = append(offline, t.String())
+		}
+	}
+	if len(offline) == 0 {
+		return ""
+	}
+	return i18n.T(lang, i18n.WorkerOffline, strings.Join(offline, ", "))
+}
+
+// ExpiryEnabled is false when no ttl is configured.
+func (m *Mng) ExpiryEnabled() bool {
+	return len(m.ttl) > 0
+}
+
+// ExpireTasks moves tasks nobody claimed within the ttl of their type
+// to expired and tells the user.
+func (m *Mng) ExpireTasks(_ context.Context) error {
+	tasks, err := m.repo.ListTasksByStatus(schema.TaskStatusCreate)
+	if err != nil {
+		return fmt.Errorf("expireTasks list: %w", err)
+	}
+	for _, task := range tasks {
+		ttl, ok := m.ttl[task.Type]
+		if !ok || time.Since(task.CreatedAt) < ttl {
+			continue
+		}
+		err = m.inTx(func(m *Mng) error {
+			return m.expireTask(task.Id, ttl)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mng) expireTask(taskId int64, ttl time.Duration) error {
+	// a worker may have claimed it since the list
+	task, err := m.repo.GetTaskById(taskId)
+	if err != nil {
+		return fmt.Errorf("expireTask get %d: %w", taskId, err)
+	}
+	if task.Status != schema.TaskStatusCreate {
+		return nil
+	}
+
+	task.Status = schema.TaskStatusExpired
+	err = m.repo.UpdateTaskStatus(task)
+	if err != nil {
+		return fmt.Errorf("expireTask update %d: %w", taskId, err)
+	}
+	err = m.addEvent(task, schema.TaskEventExpired, "", fmt.Sprintf("not claimed in %s", ttl))
+	if err != nil {
+		return fmt.Errorf("expireTask: %w", err)
+	}
+
+	if task.DialogId == 0 {
+		return nil
+	}
+	dialog, err := m.repo.GetDialogById(task.DialogId)
+	if err != nil {
+		return fmt.Errorf("expireTask get dialog %d: %w", task.DialogId, err)
+	}
+	dialog.DialogStatus = schema.DialogStatusError
+	// the user can not get a message when tbot is the missing worker
+	if task.Type != schema.TaskTypeMsg && len(dialog.Messages) > 0 {
+		err = m.reply(&dialog, task.Id, i18n.TaskExpired, task.Type.String(), ttl.String())
+		if err != nil {
+			return fmt.Errorf("expireTask: %w", err)
+		}
+	}
+	err = m.repo.UpdateDialog(dialog)
+	if err != nil {
+		return fmt.Errorf("expireTask update dialog %d: %w", dialog.Id, err)
+	}
+	return nil
+}
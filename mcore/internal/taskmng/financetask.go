@@ -1,42 +1,26 @@
-package taskmng
-
-import (
-	"fmt"
-	"strings"
-
-	"github.com/ishua/a3bot6/mcore/pkg/schema"
-)
-
-func (m *Mng) createFinanceTask(dialogId int64, userText string) (string, error) {
-	words := strings.Split(userText, " ")
-	if len(words) < 2 {
-		return "", fmt.Errorf("for finance need command")
-	}
-
-	task := schema.Task{
-		DialogId: dialogId,
-		Type:     schema.TaskTypeFinance,
-		Status:   schema.TaskStatusCreate,
-		TaskData: schema.TaskData{
-			Fin: schema.FinanceTask{},
-		},
-	}
-
-	switch words[1] {
-	case "run", "r":
-		task.TaskData.Fin.Command = "run"
-	case "load", "l":
-		task.TaskData.Fin.Command = "load"
-	case "transactions", "t":
-		task.TaskData.Fin.Command = "transactions"
-	default:
-		return "", fmt.Errorf("unknown command")
-	}
-
-	_, err := m.repo.AddTask(task)
-	if err != nil {
-		return "", fmt.Errorf("taskMng add task: %w", err)
-	}
-
-	return "task note created", nil
-}
+package taskmng
+
+import (
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func (m *Mng) createFinanceTask(c call) (string, error) {
+	task := schema.Task{
+		DialogId: c.dialogId,
+		Type:     schema.TaskTypeFinance,
+		Status:   schema.TaskStatusCreate,
+		TaskData: schema.TaskData{
+			Fin: schema.FinanceTask{
+				Command: c.path[len(c.path)-1],
+			},
+		},
+	}
+
+	err := m.addUserTask(c, task)
+	if err != nil {
+		return "", err
+	}
+
+	return c.t(i18n.TaskCreated, "finance"), nil
+}
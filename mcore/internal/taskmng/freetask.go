@@ -1,5 +1,7 @@
 package taskmng
 
-func (m *Mng) createFreeTask(dialogId int64) (string, error) {
-	return "need todo", nil
+import "github.com/ishua/a3bot6/mcore/pkg/i18n"
+
+func (m *Mng) createFreeTask(c call) (string, error) {
+	return c.t(i18n.NotDoneYet), nil
 }
@@ -0,0 +1,216 @@
+package taskmng
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const (
+	historyDefault = 10
+	historyMax     = 50
+	// historyScan is how many last dialogs /history <command> looks through
+	historyScan = 200
+	// historyText cuts texts of dialogs to keep a line short
+	historyText = 60
+)
+
+// listStatus lists tasks of the user waiting for a worker or in work,
+// replies the bot sends are no tasks for the user.
+func (m *Mng) listStatus(c call) (string, error) {
+	if c.userId == 0 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.HistoryNeedId)
+	}
+	var open []schema.Task
+	for _, status := range []schema.TaskStatus{schema.TaskStatusCreate, schema.TaskStatusSended} {
+		tasks, err := m.repo.ListUserTasks(c.userId, status)
+		if err != nil {
+			return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng status: %w", err)
+		}
+		for _, task := range tasks {
+			if task.Type != schema.TaskTypeMsg {
+				open = append(open, task)
+			}
+		}
+	}
+	if len(open) == 0 {
+		return c.t(i18n.StatusNone), nil
+	}
+	slices.SortFunc(open, func(a, b schema.Task) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	var b strings.Builder
+	b.WriteString(c.t(i18n.StatusTitle))
+	now := time.Now()
+	for _, task := range open {
+		what := task.Type.String()
+		if cmd := task.Command(); cmd != "" {
+			what += " " + cmd
+		}
+		fmt.Fprintf(&b, "\n#%d %s, %s, %s", task.Id, what, c.t(i18n.Ago, age(now.Sub(task.CreatedAt))), taskStatusText(c.lang, task.Status))
+	}
+	return b.String(), nil
+}
+
+// listHistory lists the last dialogs of the user with how they ended,
+// the filter is how many or a command whose dialogs to list.
+func (m *Mng) listHistory(c call) (string, error) {
+	if c.userId == 0 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.HistoryNeedId)
+	}
+	limit, filter := historyDefault, c.arg("filter")
+	if n, err := strconv.Atoi(filter); err == nil {
+		if n <= 0 {
+			return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.HistoryCount)
+		}
+		limit, filter = min(n, historyMax), ""
+	}
+	var want []string
+	if filter != "" {
+		want = m.commands.commandPath(filter)
+		if len(want) == 0 {
+			return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.CommandNotFound, filter)
+		}
+	}
+
+	aliases, err := m.userAliases(c.userId)
+	if err != nil {
+		return "", err
+	}
+	// one more for the dialog of this command
+	scan := limit + 1
+	if want != nil {
+		scan = historyScan
+	}
+	dialogs, err := m.repo.ListUserDialogs(c.userId, scan)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng history: %w", err)
+	}
+
+	var lines []string
+	now := time.Now()
+	for _, d := range dialogs {
+		if len(lines) == limit {
+			break
+		}
+		if d.Id == c.dialogId || len(d.Messages) == 0 {
+			continue
+		}
+		text := d.Messages[0].Text
+		if text == "" {
+			text = d.Messages[0].Caption
+		}
+		if want != nil {
+			expanded, err := expandAliases(text, aliases)
+			if err != nil || !hasPrefix(m.commands.commandPath(expanded), want) {
+				continue
+			}
+		}
+		outcome, err := m.outcome(d, c.lang)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s %s: %s", d.Id, c.t(i18n.Ago, age(now.Sub(d.CreatedAt))), shorten(text), outcome))
+	}
+	if len(lines) == 0 {
+		return c.t(i18n.HistoryNone), nil
+	}
+	return c.t(i18n.HistoryTitle) + "\n" + strings.Join(lines, "\n"), nil
+}
+
+// outcome tells how the dialog ended: the state of its last task or of the
+// dialog itself, with the last message of the bot in it or the question it
+// waits on. Dialogs older than transcripts have their replies in tasks.
+func (m *Mng) outcome(d schema.Dialog, lang i18n.Lang) (string, error) {
+	tasks, err := m.repo.ListDialogTasks(d.Id)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng history tasks of %d: %w", d.Id, err)
+	}
+	var state, text string
+	for _, task := range tasks {
+		if task.Type == schema.TaskTypeMsg {
+			text = task.TaskData.Msg.Text
+			continue
+		}
+		state = taskStatusText(lang, task.Status)
+	}
+	for _, msg := range d.Messages {
+		if msg.Type == schema.MessageTypeBot {
+			text = msg.Text
+		}
+	}
+	switch {
+	case d.DialogStatus == schema.DialogStatusWaitReply:
+		state, text = i18n.T(lang, i18n.HistoryWaiting), d.State.Question
+	case state != "":
+	case d.DialogStatus == schema.DialogStatusError:
+		state = i18n.T(lang, i18n.HistoryFailed)
+	case d.DialogStatus == schema.DialogStatusClose:
+		state = i18n.T(lang, i18n.HistoryClosed)
+	default:
+		state = i18n.T(lang, i18n.HistoryAnswered)
+	}
+	if text == "" {
+		return state, nil
+	}
+	return state + ", " + shorten(text), nil
+}
+
+// commandPath returns names of the commands text begins with, e.g.
+// ds add for dsm film, nil when text begins with no command.
+func (r *registry) commandPath(text string) []string {
+	var path []string
+	l := newLexer(r.expand(text))
+	cmds := r.commands
+	for len(cmds) > 0 {
+		word, ok, err := l.next()
+		if err != nil || !ok {
+			break
+		}
+		cmd := findCommand(cmds, word)
+		if cmd == nil {
+			break
+		}
+		path = append(path, cmd.name)
+		cmds = cmd.subs
+	}
+	return path
+}
+
+func hasPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+func taskStatusText(lang i18n.Lang, status schema.TaskStatus) string {
+	return i18n.T(lang, i18n.Status(status.String()))
+}
+
+// age rounds d to the largest unit, e.g. 5m or 3d.
+func age(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// shorten puts text on one line and cuts it to historyText runes.
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= historyText {
+		return text
+	}
+	return string(runes[:historyText-1]) + "…"
+}
@@ -0,0 +1,150 @@
+package taskmng
+
+import (
+	"maps"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// intentArg holds the path of the guessed command among its args
+// while the user confirms it.
+const intentArg = "intent"
+
+// intent is a command guessed from a message without one.
+type intent struct {
+	path     string
+	question i18n.Key
+	// match returns args of the command when the message looks like it
+	match func(text, fileUrl string) (map[string]string, bool)
+}
+
+// intents are tried in order, the first match wins.
+var intents = []intent{
+	{path: "y2d", question: i18n.IntentYoutube, match: matchYoutube},
+	{path: "ds add", question: i18n.IntentTorrent, match: matchTorrent},
+	{path: "note inbox add", question: i18n.IntentInbox, match: matchText},
+}
+
+func findIntent(path string) (intent, bool) {
+	for _, in := range intents {
+		if in.path == path {
+			return in, true
+		}
+	}
+	return intent{}, false
+}
+
+func matchYoutube(text, _ string) (map[string]string, bool) {
+	u, ok := bareUrl(text)
+	if !ok || !isYoutube(u.Host) {
+		return nil, false
+	}
+	return map[string]string{"link": text}, true
+}
+
+func matchTorrent(text, fileUrl string) (map[string]string, bool) {
+	if text == "" {
+		u, err := url.Parse(fileUrl)
+		return map[string]string{}, err == nil && path.Ext(u.Path) == ".torrent"
+	}
+	if strings.HasPrefix(text, "magnet:?") && !strings.ContainsAny(text, " \t\n") {
+		return map[string]string{"url": text}, true
+	}
+	u, ok := bareUrl(text)
+	if !ok || path.Ext(u.Path) != ".torrent" {
+		return nil, false
+	}
+	return map[string]string{"url": text}, true
+}
+
+func matchText(text, _ string) (map[string]string, bool) {
+	return map[string]string{"text": text}, text != ""
+}
+
+// bareUrl parses text that is a single http link.
+func bareUrl(text string) (*url.URL, bool) {
+	if strings.ContainsAny(text, " \t\n") {
+		return nil, false
+	}
+	u, err := url.Parse(text)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// intentCall guesses the command of a message that starts with no command,
+// nil when the message has a command, the user turned guessing off or
+// nothing fits. A guess runs at once in the auto mode, else the hidden
+// intent command asks the user first. Only a message starting with / may
+// be a typo of a command, short texts are close to some command too. Text
+// the lexer can not split, e.g. with an open quote, is no command either.
+func (m *Mng) intentCall(text, fileUrl string, user call) (*command, call, error) {
+	mode := user.settings[schema.SettingIntents]
+	if mode == schema.IntentsOff {
+		return nil, user, nil
+	}
+	text = strings.TrimSpace(text)
+	if text != "" {
+		word, ok, err := newLexer(text).next()
+		if err == nil && (!ok || m.commands.known(word)) {
+			return nil, user, nil
+		}
+	}
+	allow := func(p access.Perm) bool {
+		return m.access.Grants(user.role, p)
+	}
+	if strings.HasPrefix(text, "/") {
+		if _, ok := m.commands.suggest(text, allow); ok {
+			// a typo of a command is no text for the inbox
+			return nil, user, nil
+		}
+	}
+
+	for _, in := range intents {
+		args, ok := in.match(text, fileUrl)
+		if !ok {
+			continue
+		}
+		cmd := m.commands.lookup(strings.Fields(in.path))
+		if cmd == nil || !cmd.allowed(allow) {
+			continue
+		}
+		c := user
+		if mode == schema.IntentsAuto {
+			c.path = strings.Fields(in.path)
+			c.args = args
+			return cmd, c, parseArgs(newLexer(""), cmd, c, 0)
+		}
+		c.path = []string{intentArg}
+		c.args = args
+		c.args[intentArg] = in.path
+		return m.commands.lookup(c.path), c, nil
+	}
+	return nil, user, nil
+}
+
+// confirmIntent asks whether to run the guessed command and hands the
+// call over to it on yes.
+func (m *Mng) confirmIntent(c call) (string, error) {
+	in, ok := findIntent(c.arg(intentArg))
+	if !ok {
+		return "", schema.Localized(schema.ErrCodeUnknownCommand, i18n.CommandNotFound, usagePath(c.path))
+	}
+	switch {
+	case c.arg("confirm") == "":
+		return "", &ask{arg: "confirm", question: c.t(in.question), buttons: yesNoButtons(c)}
+	case isYes(c.arg("confirm")):
+		args := maps.Clone(c.args)
+		delete(args, intentArg)
+		delete(args, "confirm")
+		return "", &redirect{path: strings.Fields(in.path), args: args}
+	default:
+		return c.t(i18n.Cancelled), nil
+	}
+}
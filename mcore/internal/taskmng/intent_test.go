@@ -0,0 +1,70 @@
+package taskmng
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestIntentCall(t *testing.T) {
+	m := newTestMng(t, Config{})
+	tests := []struct {
+		name    string
+		text    string
+		fileUrl string
+		mode    string
+		// want is the path of the guessed command, "" for no guess
+		want string
+		args map[string]string
+	}{
+		{name: "youtube", text: "https://youtu.be/dQw4w9WgXcQ", want: "y2d", args: map[string]string{"link": "https://youtu.be/dQw4w9WgXcQ"}},
+		{name: "magnet", text: "magnet:?xt=urn:btih:abc", want: "ds add", args: map[string]string{"url": "magnet:?xt=urn:btih:abc"}},
+		{name: "torrent link", text: "https://example.com/a.torrent", want: "ds add"},
+		{name: "torrent file", fileUrl: "https://api.telegram.org/file/a.torrent", want: "ds add"},
+		{name: "text", text: "buy milk", want: "note inbox add", args: map[string]string{"text": "buy milk"}},
+		{name: "short text close to a command", text: "no", want: "note inbox add"},
+		{name: "short text close to an alias", text: "hi", want: "note inbox add"},
+		{name: "open quote", text: `"buy milk`, want: "note inbox add", args: map[string]string{"text": `"buy milk`}},
+		{name: "open single quote", text: "'tis", want: "note inbox add"},
+		{name: "command", text: "/note inbox add milk"},
+		{name: "command without slash", text: "ping"},
+		{name: "typo of a command", text: "/nte inbox add milk"},
+		{name: "off", text: "buy milk", mode: schema.IntentsOff},
+		{name: "empty", text: "  "},
+		{name: "auto", text: "buy milk", mode: schema.IntentsAuto, want: "note inbox add"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := schema.Settings{}
+			if tt.mode != "" {
+				settings[schema.SettingIntents] = tt.mode
+			}
+			cmd, c, err := m.intentCall(tt.text, tt.fileUrl, testCall(1, settings))
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+			if tt.want == "" {
+				if cmd != nil {
+					t.Fatalf("guessed %v, want none", c.path)
+				}
+				return
+			}
+			if cmd == nil {
+				t.Fatalf("no guess, want %s", tt.want)
+			}
+			got := strings.Join(c.path, " ")
+			if tt.mode != schema.IntentsAuto {
+				got = c.arg(intentArg)
+			}
+			if got != tt.want {
+				t.Fatalf("guessed %s, want %s", got, tt.want)
+			}
+			for k, v := range tt.args {
+				if c.arg(k) != v {
+					t.Errorf("arg %s = %q, want %q", k, c.arg(k), v)
+				}
+			}
+		})
+	}
+}
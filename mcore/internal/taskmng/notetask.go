@@ -1,139 +1,31 @@
 package taskmng
 
 import (
-	"fmt"
-	"strings"
-
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
-func (m *Mng) createNoteTask(dialogId int64, text string) (string, error) {
-	words := strings.Split(text, " ")
-	if len(words) < 2 {
-		return "", fmt.Errorf("for note need command")
-	}
-	var err error
-	if words[0] != "/note" {
-		words, err = parseSimplifications(words)
-		if err != nil {
-			return "", err
+// noteTask makes the handler of a note sub command, the notes worker gets
+// the command and the text as is.
+func noteTask(cmd schema.TaskNoteCmd) handler {
+	return func(m *Mng, c call) (string, error) {
+		task := schema.Task{
+			DialogId: c.dialogId,
+			Type:     schema.TaskTypeNote,
+			Status:   schema.TaskStatusCreate,
+			TaskData: schema.TaskData{
+				Tn: schema.TaskNote{
+					Command: cmd,
+					AddText: c.arg("text"),
+				},
+			},
 		}
-	}
 
-	task := schema.Task{
-		DialogId: dialogId,
-		Type:     schema.TaskTypeNote,
-		Status:   schema.TaskStatusCreate,
-		TaskData: schema.TaskData{
-			Tn: schema.TaskNote{},
-		},
-	}
-	switch words[1] {
-	case "5bx":
-		{
-			var addText string
-			if len(words) > 2 {
-				addText = strings.Join(words[2:], " ")
-			}
-			task.TaskData.Tn = schema.TaskNote{
-				Command: schema.TaskNoteCmdAdd5bx,
-				AddText: addText,
-			}
-		}
-	case "entry":
-		{
-			var addText string
-			if len(words) > 2 {
-				addText = strings.Join(words[2:], " ")
-			}
-			task.TaskData.Tn = schema.TaskNote{
-				Command: schema.TaskNoteCmdAddEntry,
-				AddText: addText,
-			}
-		}
-	case "inbox":
-		{
-			switch words[2] {
-			case "add":
-				{
-					var addText string
-					if len(words) > 3 {
-						addText = strings.Join(words[3:], " ")
-					}
-					task.TaskData.Tn = schema.TaskNote{
-						Command: schema.TaskNoteCmdAddInbox,
-						AddText: addText,
-					}
-				}
-			case "read":
-				{
-					task.TaskData.Tn = schema.TaskNote{
-						Command: schema.TaskNoteReadInbox,
-					}
-				}
-			default:
-				return "", fmt.Errorf("for inbox need label")
-			}
-		}
-	case "pull":
-		{
-			task.TaskData.Tn = schema.TaskNote{
-				Command: schema.TaskNoteCmdPull,
-				AddText: "",
-			}
-		}
-	case "weight":
-		{
-			var addText string
-			if len(words) > 2 {
-				addText = strings.Join(words[2:], " ")
-			}
-			task.TaskData.Tn = schema.TaskNote{
-				Command: schema.TaskNoteCmdAddWeight,
-				AddText: addText,
-			}
-		}
-	case "bp":
-		{
-			var addText string
-			if len(words) > 2 {
-				addText = strings.Join(words[2:], " ")
-			}
-			task.TaskData.Tn = schema.TaskNote{
-				Command: schema.TaskNoteCmdAddBP,
-				AddText: addText,
-			}
-		}
-	case "help":
-		{
-			return tnHelpText, nil
+		err := m.addUserTask(c, task)
+		if err != nil {
+			return "", err
 		}
-	default:
-		return "", fmt.Errorf("unknown command")
-	}
-
-	_, err = m.repo.AddTask(task)
-	if err != nil {
-		return "", fmt.Errorf("taskMng add task: %w", err)
-	}
-
-	return "task note created", nil
-}
 
-func parseSimplifications(words []string) ([]string, error) {
-	switch words[0] {
-	case "nd", "Nd":
-		return append([]string{"/note", "entry"}, words[1:]...), nil
-	case "n5", "N5":
-		return append([]string{"/note", "5bx"}, words[1:]...), nil
-	case "ni", "Ni":
-		return append([]string{"/note", "inbox", "add"}, words[1:]...), nil
-	case "nir", "Nir":
-		return append([]string{"/note", "inbox", "read"}, words[1:]...), nil
-	case "nw", "Nw":
-		return append([]string{"/note", "weight"}, words[1:]...), nil
-	case "nbp", "Nbp":
-		return append([]string{"/note", "bp"}, words[1:]...), nil
+		return c.t(i18n.TaskCreated, "note"), nil
 	}
-	return nil, fmt.Errorf("synonyms command not found")
 }
@@ -0,0 +1,58 @@
+package taskmng
+
+import (
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func parseQuotas(cfg map[string]int) (map[schema.TaskType]int, error) {
+	quotas := map[schema.TaskType]int{}
+	for name, n := range cfg {
+		t, ok := schema.ParseTaskType(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown task type %s", name)
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("quota for %s must be positive", name)
+		}
+		quotas[t] = n
+	}
+	return quotas, nil
+}
+
+// checkQuotas refuses a new task of taskType when the user or all users
+// together already have as many open ones as the config allows. The caller
+// adds the task in the same transaction.
+func (m *Mng) checkQuotas(c call, taskType schema.TaskType) error {
+	userQuota, concurrency := m.userQuotas[taskType], m.concurrency[taskType]
+	if userQuota == 0 && concurrency == 0 {
+		return nil
+	}
+	all, mine, err := m.openTasks(c, taskType)
+	if err != nil {
+		return err
+	}
+	if concurrency > 0 && all >= concurrency {
+		return schema.Localized(schema.ErrCodeRateLimited, i18n.BusyReached, all, taskType.String(), concurrency)
+	}
+	if userQuota > 0 && mine >= userQuota {
+		return schema.Localized(schema.ErrCodeRateLimited, i18n.QuotaReached, mine, taskType.String(), userQuota)
+	}
+	return nil
+}
+
+// openTasks counts tasks of taskType waiting for a worker or in work, mine
+// are the ones from dialogs of the user of c. Senders without an id have
+// no dialogs of their own to count.
+func (m *Mng) openTasks(c call, taskType schema.TaskType) (all, mine int, err error) {
+	all, mine, err = m.repo.CountOpenTasks(taskType, c.userId)
+	if err != nil {
+		return 0, 0, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng open tasks: %w", err)
+	}
+	if c.userId == 0 {
+		mine = 0
+	}
+	return all, mine, nil
+}
@@ -0,0 +1,125 @@
+package taskmng
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/storage/memstore"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestParseQuotas(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]int
+		want    map[schema.TaskType]int
+		wantErr bool
+	}{
+		{"none", nil, map[schema.TaskType]int{}, false},
+		{"types", map[string]int{"ytdl": 3, "finance": 1}, map[schema.TaskType]int{schema.TaskTypeYtdl: 3, schema.TaskTypeFinance: 1}, false},
+		{"unknown type", map[string]int{"video": 3}, nil, true},
+		{"zero", map[string]int{"ytdl": 0}, nil, true},
+		{"negative", map[string]int{"ytdl": -1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuotas(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("quotas = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// addOpenTask adds a task of taskType in status to a new dialog of userId.
+func addOpenTask(t *testing.T, m *Mng, userId int64, taskType schema.TaskType, status schema.TaskStatus) {
+	t.Helper()
+	dialogId, err := m.repo.(*memstore.MemStore).AddDialog(schema.Dialog{Messages: []schema.Message{{UserId: userId}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.repo.AddTask(schema.Task{Type: taskType, Status: status, DialogId: dialogId})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	m := newTestMng(t, Config{
+		UserQuotas:  map[string]int{"ytdl": 2},
+		Concurrency: map[string]int{"ytdl": 4, "finance": 1},
+	})
+	addOpenTask(t, m, 1, schema.TaskTypeYtdl, schema.TaskStatusCreate)
+	addOpenTask(t, m, 1, schema.TaskTypeYtdl, schema.TaskStatusSended)
+	addOpenTask(t, m, 2, schema.TaskTypeYtdl, schema.TaskStatusSended)
+	addOpenTask(t, m, 2, schema.TaskTypeYtdl, schema.TaskStatusDone)
+	addOpenTask(t, m, 2, schema.TaskTypeFinance, schema.TaskStatusCreate)
+
+	tests := []struct {
+		name     string
+		userId   int64
+		taskType schema.TaskType
+		want     string
+	}{
+		{"user quota", 1, schema.TaskTypeYtdl, "you have 2 open ytdl tasks, the limit is 2"},
+		{"done tasks do not count", 2, schema.TaskTypeYtdl, ""},
+		{"no id has no own tasks", 0, schema.TaskTypeYtdl, ""},
+		{"concurrency", 3, schema.TaskTypeFinance, "1 finance tasks are running, the limit is 1"},
+		{"no quota", 1, schema.TaskTypeTorrent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.checkQuotas(testCall(tt.userId, nil), tt.taskType)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if schema.CodeOf(err) != schema.ErrCodeRateLimited {
+				t.Fatalf("err = %v, want rate limited", err)
+			}
+			if got := schema.Translate(err, i18n.En).Error(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("err = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	addOpenTask(t, m, 2, schema.TaskTypeYtdl, schema.TaskStatusCreate)
+	err := m.checkQuotas(testCall(3, nil), schema.TaskTypeYtdl)
+	if schema.CodeOf(err) != schema.ErrCodeRateLimited {
+		t.Errorf("err = %v, want ytdl busy with 4 open tasks", err)
+	}
+}
+
+func TestAddUserTaskQuota(t *testing.T) {
+	m := newTestMng(t, Config{UserQuotas: map[string]int{"ytdl": 2}})
+	addOpenTask(t, m, 1, schema.TaskTypeYtdl, schema.TaskStatusCreate)
+	dialogId, err := m.repo.(*memstore.MemStore).AddDialog(schema.Dialog{Messages: []schema.Message{{UserId: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := testCall(1, nil)
+	task := schema.Task{Type: schema.TaskTypeYtdl, Status: schema.TaskStatusCreate, DialogId: dialogId}
+	err = m.addUserTask(c, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.addUserTask(c, task)
+	if schema.CodeOf(err) != schema.ErrCodeRateLimited {
+		t.Fatalf("err = %v, want rate limited", err)
+	}
+	tasks, err := m.repo.ListUserTasks(1, schema.TaskStatusCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("open tasks = %d, want 2", len(tasks))
+	}
+}
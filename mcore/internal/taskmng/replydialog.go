@@ -0,0 +1,120 @@
+package taskmng
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// ProcessDialogReply continues a dialog that waits for a reply, the answer
+// is the last message of the dialog, a typed reply or a pressed button.
+func (m *Mng) ProcessDialogReply(dialogId int64) (schema.TaskMsg, error) {
+	dialog, err := m.repo.GetDialogById(dialogId)
+	if err != nil {
+		return schema.TaskMsg{}, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng get dialog by id: %w", err)
+	}
+	if dialog.DialogStatus != schema.DialogStatusWaitReply {
+		return schema.TaskMsg{}, fmt.Errorf("wrong dialog status")
+	}
+
+	answer := dialog.Messages[len(dialog.Messages)-1]
+	text := answer.Text
+	if len(text) == 0 {
+		text = answer.Caption
+	}
+	state := dialog.State
+	lang := m.Lang(dialog.Messages[0])
+
+	if isCancel(text) {
+		reply := schema.TaskMsg{Text: i18n.T(lang, i18n.Cancelled)}
+		err = m.changeDialog(dialogId, func(dialog *schema.Dialog) {
+			dialog.DialogStatus = schema.DialogStatusClose
+			dialog.State = schema.DialogState{}
+			appendBot(dialog, answer.ChatId, reply.Text, 0)
+		})
+		if err != nil {
+			return schema.TaskMsg{}, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng close dialog: %w", err)
+		}
+		return reply, nil
+	}
+
+	cmd := m.commands.lookup(state.Path)
+	if cmd == nil || cmd.run == nil {
+		err = fmt.Errorf("dialog %d waits for unknown command %v", dialogId, state.Path)
+		return schema.TaskMsg{}, m.failDialog(dialogId, err, errorReply(err, lang))
+	}
+
+	c, err := m.userCall(dialog.Messages[0])
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	c.dialogId = dialogId
+	c.fileUrl = state.FileUrl
+	c.path = state.Path
+	maps.Copy(c.args, state.Args)
+	if answer.FileUrl != "" {
+		c.fileUrl = answer.FileUrl
+	}
+
+	var reply schema.TaskMsg
+	err = m.inTx(func(m *Mng) error {
+		// a rollback leaves the dialog waiting
+		d, err := m.repo.GetDialogById(dialogId)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng get dialog by id: %w", err)
+		}
+		var parseErr error
+		if i := argIndex(cmd, state.Ask); i >= 0 {
+			// the answer may also hold the args after the asked one
+			parseErr = parseArgs(newLexer(text), cmd, c, i)
+			var a *ask
+			if parseErr != nil && !errors.As(parseErr, &a) {
+				return parseErr
+			}
+		} else {
+			// the command itself asked for a value that is not an arg
+			c.args[state.Ask] = strings.TrimSpace(text)
+		}
+
+		d.DialogStatus = schema.DialogStatusBegin
+		d.State = schema.DialogState{}
+		err = m.repo.UpdateDialog(d)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng continue dialog: %w", err)
+		}
+		reply, err = m.runCommand(&d, cmd, c, parseErr)
+		if err != nil {
+			return err
+		}
+		if warning := m.offlineWarning(*m.added, c.lang); warning != "" {
+			reply.Text += "\n" + warning
+		}
+		return m.record(&d, answer.ChatId, reply.Text)
+	})
+	if schema.IsUserError(err) {
+		// a wrong answer keeps the dialog waiting, the user may answer again
+		msg := askMsg(c.lang, state.Question, state.Buttons)
+		msg.Text = schema.Translate(err, c.lang).Error() + "\n" + msg.Text
+		err = m.changeDialog(dialogId, func(dialog *schema.Dialog) {
+			appendBot(dialog, answer.ChatId, msg.Text, 0)
+		})
+		if err != nil {
+			return msg, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng record reply: %w", err)
+		}
+		return msg, nil
+	}
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	return reply, nil
+}
+
+// isCancel reports whether the reply stops the dialog, in any language.
+func isCancel(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.EqualFold(text, "cancel") || strings.EqualFold(text, "отмена")
+}
@@ -2,48 +2,146 @@ package taskmng
 
 import (
 	"fmt"
+	"strconv"
 
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
-func (m *Mng) ReportTask(taskId int64, status schema.TaskStatus, msg string) error {
+// reportEvents maps a status reported by a worker to the event it logs.
+// Create gives the task back to the queue for another try.
+var reportEvents = map[schema.TaskStatus]schema.TaskEventKind{
+	schema.TaskStatusCreate: schema.TaskEventRetried,
+	schema.TaskStatusSended: schema.TaskEventProgress,
+	schema.TaskStatusDone:   schema.TaskEventDone,
+	schema.TaskStatusError:  schema.TaskEventError,
+}
+
+// ReportTask saves the task result, updates the dialog and queues the reply
+// in one transaction.
+func (m *Mng) ReportTask(rt schema.ReportTaskReq) error {
+	return m.inTx(func(m *Mng) error {
+		return m.reportTask(rt)
+	})
+}
+
+func (m *Mng) reportTask(rt schema.ReportTaskReq) error {
+	taskId, status, msg := rt.TaskId, rt.Status, rt.TextMsg
 	task, err := m.repo.GetTaskById(taskId)
 	if err != nil {
-		return fmt.Errorf("reportTask getTask err: %w", err)
+		return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask getTask err: %w", err)
 	}
-	dialog, err := m.repo.GetDialogById(task.DialogId)
-	if err != nil {
-		return fmt.Errorf("reportTask getDialog err: %w", err)
+	if task.Id == 0 {
+		return schema.Errorf(schema.ErrCodeNotFound, "reportTask task %d not found", taskId)
+	}
+	event, ok := reportEvents[status]
+	if !ok {
+		return schema.Errorf(schema.ErrCodeInvalidArgument, "reportTask wrong status %d", status)
+	}
+	if task.Status == schema.TaskStatusCancelled {
+		return schema.Errorf(schema.ErrCodeInvalidArgument, "reportTask task %d is cancelled", taskId)
 	}
 
 	task.Status = status
 	err = m.repo.UpdateTaskStatus(task)
 	if err != nil {
-		return fmt.Errorf("reportTask updateTaskStatus err: %w", err)
+		return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask updateTaskStatus err: %w", err)
+	}
+	err = m.addEvent(task, event, rt.Worker, msg)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask: %w", err)
+	}
+
+	// a worker sends the key of its text so the user reads it in their language
+	key, args := i18n.WorkerText, []any{msg}
+	if rt.MsgKey != "" {
+		key, args = i18n.Key(rt.MsgKey), nil
+		for _, a := range rt.MsgArgs {
+			args = append(args, a)
+		}
 	}
+	hasText := rt.MsgKey != "" || msg != ""
+	progress := status == schema.TaskStatusSended || status == schema.TaskStatusCreate
 
-	if status == schema.TaskStatusSended {
+	// notices from Notify belong to no dialog, replies are in the transcript
+	// since they were queued
+	if task.DialogId == 0 || (progress && (!hasText || task.Type == schema.TaskTypeMsg)) {
 		return nil
 	}
 
-	if status == schema.TaskStatusError {
-		dialog.DialogStatus = schema.DialogStatusError
+	dialog, err := m.repo.GetDialogById(task.DialogId)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask getDialog err: %w", err)
 	}
 
-	if status == schema.TaskStatusDone {
+	switch {
+	case progress:
+		// the user gets only the result, progress goes to the transcript
+		settings, err := m.dialogSettings(dialog)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask: %w", err)
+		}
+		text := i18n.T(langOf(settings, dialog.Messages[0].LanguageCode), key, args...)
+		appendBot(&dialog, lastUserMessage(dialog).ChatId, text, task.Id)
+	case status == schema.TaskStatusError:
+		dialog.DialogStatus = schema.DialogStatusError
+	case status == schema.TaskStatusDone:
 		dialog.DialogStatus = schema.DialogStatusClose
 	}
+
+	if !progress && task.Type != schema.TaskTypeMsg && hasText {
+		err = m.reply(&dialog, task.Id, key, args...)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask: %w", err)
+		}
+	}
 	err = m.repo.UpdateDialog(dialog)
 	if err != nil {
-		return fmt.Errorf("reportTask updateDialog err: %w", err)
+		return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask updateDialog err: %w", err)
 	}
 
-	if task.Type == schema.TaskTypeMsg {
-		return nil
+	// a reply to the sent message finds the dialog
+	if task.Type == schema.TaskTypeMsg && rt.MessageId != 0 {
+		err = m.repo.AddDialogRef(task.TaskData.Msg.ChatId, rt.MessageId, dialog.Id)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "reportTask addDialogRef err: %w", err)
+		}
 	}
+	return nil
+}
 
-	if msg == "" {
-		return nil
+// dialogSettings returns the settings of the user who began the dialog.
+func (m *Mng) dialogSettings(dialog schema.Dialog) (schema.Settings, error) {
+	userId := dialog.UserId()
+	if userId == 0 {
+		return nil, nil
+	}
+	settings, err := m.repo.ListUserSettings(userId)
+	if err != nil {
+		return nil, fmt.Errorf("settings of %d: %w", userId, err)
+	}
+	return settings, nil
+}
+
+// reply queues the text of key in the language of the user of the dialog
+// as an answer to their last message, or into their notify chat when they
+// have set one. The text goes to the transcript of the dialog as a report
+// on taskId, the caller saves the dialog.
+func (m *Mng) reply(dialog *schema.Dialog, taskId int64, key i18n.Key, args ...any) error {
+	last := lastUserMessage(*dialog)
+	msg := schema.TaskMsg{
+		ChatId:         last.ChatId,
+		ReplyMessageId: last.MessageId,
+	}
+
+	settings, err := m.dialogSettings(*dialog)
+	if err != nil {
+		return fmt.Errorf("reply: %w", err)
+	}
+	msg.Text = i18n.T(langOf(settings, dialog.Messages[0].LanguageCode), key, args...)
+	if chatId := settings[schema.SettingNotifyChat]; chatId != "" {
+		msg.ChatId, _ = strconv.ParseInt(chatId, 10, 64)
+		msg.ReplyMessageId = 0
 	}
 
 	replyTask := schema.Task{
@@ -51,16 +149,98 @@ func (m *Mng) ReportTask(taskId int64, status schema.TaskStatus, msg string) err
 		Type:     schema.TaskTypeMsg,
 		Status:   schema.TaskStatusCreate,
 		TaskData: schema.TaskData{
-			Msg: schema.TaskMsg{
-				ChatId:         dialog.Messages[0].ChatId,
-				ReplyMessageId: dialog.Messages[0].MessageId,
-				Text:           msg,
-			},
+			Msg:      msg,
+			Settings: workerSettings(settings, schema.TaskTypeMsg),
 		},
 	}
-	_, err = m.repo.AddTask(replyTask)
+	_, err = m.addTask(replyTask)
+	if err != nil {
+		return fmt.Errorf("reply addTask err: %w", err)
+	}
+	appendBot(dialog, msg.ChatId, msg.Text, taskId)
+	return nil
+}
+
+// CancelTask stops a task nobody has finished yet, a worker can not report
+// it afterwards.
+func (m *Mng) CancelTask(taskId int64, reason string) error {
+	return m.inTx(func(m *Mng) error {
+		task, err := m.repo.GetTaskById(taskId)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "cancelTask getTask err: %w", err)
+		}
+		if task.Id == 0 {
+			return schema.Errorf(schema.ErrCodeNotFound, "cancelTask task %d not found", taskId)
+		}
+		if task.Status != schema.TaskStatusCreate && task.Status != schema.TaskStatusSended {
+			return schema.Errorf(schema.ErrCodeInvalidArgument, "cancelTask task %d is already finished", taskId)
+		}
+
+		task.Status = schema.TaskStatusCancelled
+		err = m.repo.UpdateTaskStatus(task)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "cancelTask updateTaskStatus err: %w", err)
+		}
+		err = m.addEvent(task, schema.TaskEventCancelled, "", reason)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "cancelTask: %w", err)
+		}
+		return nil
+	})
+}
+
+// Notify queues a message for tbot outside of any dialog,
+// replyMessageId is 0 when it answers nothing.
+func (m *Mng) Notify(chatId int64, replyMessageId int, text string) error {
+	return m.NotifyMsg(schema.TaskMsg{
+		ChatId:         chatId,
+		ReplyMessageId: replyMessageId,
+		Text:           text,
+	})
+}
+
+// NotifyDialog is Notify for a notice about a task of the dialog in the
+// language of its user, it answers the first message of the dialog and
+// goes to its transcript.
+func (m *Mng) NotifyDialog(dialogId, taskId int64, key i18n.Key, args ...any) error {
+	return m.inTx(func(m *Mng) error {
+		dialog, err := m.repo.GetDialogById(dialogId)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "notify dialog %d: %w", dialogId, err)
+		}
+		if len(dialog.Messages) == 0 {
+			return schema.Errorf(schema.ErrCodeNotFound, "notify dialog %d has no messages", dialogId)
+		}
+		first := dialog.Messages[0]
+		settings, err := m.dialogSettings(dialog)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "notify dialog %d: %w", dialogId, err)
+		}
+		text := i18n.T(langOf(settings, first.LanguageCode), key, args...)
+		err = m.Notify(first.ChatId, first.MessageId, text)
+		if err != nil {
+			return err
+		}
+		appendBot(&dialog, first.ChatId, text, taskId)
+		err = m.repo.UpdateDialog(dialog)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "notify dialog %d: %w", dialogId, err)
+		}
+		return nil
+	})
+}
+
+// NotifyMsg is Notify for a message with buttons.
+func (m *Mng) NotifyMsg(msg schema.TaskMsg) error {
+	_, err := m.addTask(schema.Task{
+		Type:   schema.TaskTypeMsg,
+		Status: schema.TaskStatusCreate,
+		TaskData: schema.TaskData{
+			Msg: msg,
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("reportTask addTask err: %w", err)
+		return schema.Errorf(schema.ErrCodeStorageFailure, "notify chat %d: %w", msg.ChatId, err)
 	}
 	return nil
 }
@@ -0,0 +1,262 @@
+package taskmng
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// setting is a key users may set, workers of the task types get its value
+// inside the task.
+type setting struct {
+	key         string
+	summary     i18n.Key
+	summaryArgs []any
+	workers     []schema.TaskType
+	check       func(value string) error
+}
+
+var settings = []setting{
+	{
+		key: schema.SettingTimezone, summary: i18n.SettingTimezone,
+		workers: []schema.TaskType{schema.TaskTypeNote, schema.TaskTypeMsg},
+		check:   checkTimezone,
+	},
+	{
+		key: schema.SettingLanguage, summary: i18n.SettingLanguage, summaryArgs: []any{strings.Join(i18n.Langs(), ", ")},
+		check: oneOf(i18n.Langs()...),
+	},
+	{
+		key: schema.SettingDownloadCategory, summary: i18n.SettingCategory,
+		workers: []schema.TaskType{schema.TaskTypeSyno},
+		check:   oneOf(synoChoices...),
+	},
+	{
+		key: schema.SettingQuietHours, summary: i18n.SettingQuiet,
+		workers: []schema.TaskType{schema.TaskTypeMsg},
+		check: func(v string) error {
+			_, _, err := schema.ParseQuietHours(v)
+			return err
+		},
+	},
+	{key: schema.SettingNotifyChat, summary: i18n.SettingNotify, check: checkChatId},
+	{
+		key: schema.SettingIntents, summary: i18n.SettingIntents,
+		check: oneOf(schema.IntentsConfirm, schema.IntentsAuto, schema.IntentsOff),
+	},
+}
+
+func findSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func (s setting) describe(lang i18n.Lang) string {
+	return i18n.T(lang, s.summary, s.summaryArgs...)
+}
+
+func settingKeys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+func checkTimezone(v string) error {
+	_, err := time.LoadLocation(v)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %s", v)
+	}
+	return nil
+}
+
+func checkChatId(v string) error {
+	_, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("chat id %s is not a number", v)
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		if !slices.Contains(values, v) {
+			return fmt.Errorf("%s is not one of %s", v, strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
+
+// workerSettings picks the settings the worker of taskType uses.
+func workerSettings(all schema.Settings, taskType schema.TaskType) schema.Settings {
+	var ret schema.Settings
+	for _, s := range settings {
+		if all[s.key] == "" || !slices.Contains(s.workers, taskType) {
+			continue
+		}
+		if ret == nil {
+			ret = schema.Settings{}
+		}
+		ret[s.key] = all[s.key]
+	}
+	return ret
+}
+
+// userCall starts the call of a message with the role, the settings and
+// the language of its sender.
+func (m *Mng) userCall(msg schema.Message) (call, error) {
+	c := call{userId: msg.UserId, userName: msg.UserName, args: map[string]string{}}
+	var err error
+	c.role, err = m.access.Role(msg.UserId, msg.UserName)
+	if err != nil {
+		return call{}, err
+	}
+	c.settings, err = m.userSettings(msg.UserId)
+	if err != nil {
+		return call{}, err
+	}
+	c.lang = langOf(c.settings, msg.LanguageCode)
+	return c, nil
+}
+
+func (m *Mng) userSettings(userId int64) (schema.Settings, error) {
+	if userId == 0 {
+		return schema.Settings{}, nil
+	}
+	s, err := m.repo.ListUserSettings(userId)
+	if err != nil {
+		return nil, schema.Errorf(schema.ErrCodeStorageFailure, "taskMng settings of %d: %w", userId, err)
+	}
+	return s, nil
+}
+
+// langOf picks the language setting of the user, then the language of
+// their telegram.
+func langOf(settings schema.Settings, languageCode string) i18n.Lang {
+	if lang, ok := i18n.Parse(settings[schema.SettingLanguage]); ok {
+		return lang
+	}
+	if lang, ok := i18n.Parse(languageCode); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+// Lang is the language of replies to the sender of msg, the router
+// translates errors to it.
+func (m *Mng) Lang(msg schema.Message) i18n.Lang {
+	// without settings the language of telegram is still good
+	settings, _ := m.userSettings(msg.UserId)
+	return langOf(settings, msg.LanguageCode)
+}
+
+// addUserTask adds a task of the command with the settings its worker uses,
+// the quotas are checked in the transaction that adds it.
+func (m *Mng) addUserTask(c call, task schema.Task) error {
+	return m.inTx(func(m *Mng) error {
+		err := m.checkQuotas(c, task.Type)
+		if err != nil {
+			return err
+		}
+		task.TaskData.Settings = workerSettings(c.settings, task.Type)
+		_, err = m.addTask(task)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng add task: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m *Mng) listSettings(c call) (string, error) {
+	var b strings.Builder
+	b.WriteString(c.t(i18n.SettingsTitle))
+	for _, s := range settings {
+		value := c.settings[s.key]
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(&b, "\n- %s = %s, %s", s.key, value, s.describe(c.lang))
+	}
+	return b.String(), nil
+}
+
+func (m *Mng) getSetting(c call) (string, error) {
+	s, ok := findSetting(c.arg("key"))
+	if !ok {
+		return "", unknownSetting(c.arg("key"))
+	}
+	value := c.settings[s.key]
+	if value == "" {
+		return c.t(i18n.SettingNotSet, s.key, s.describe(c.lang)), nil
+	}
+	return fmt.Sprintf("%s = %s", s.key, value), nil
+}
+
+// setSetting saves the value, no value clears the setting.
+func (m *Mng) setSetting(c call) (string, error) {
+	if c.userId == 0 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.SettingsNeedId)
+	}
+	s, ok := findSetting(c.arg("key"))
+	if !ok {
+		return "", unknownSetting(c.arg("key"))
+	}
+	value := c.arg("value")
+	if value != "" && s.check(value) != nil {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.WrongSetting, s.key, value, s.describe(c.lang))
+	}
+	if s.key == schema.SettingNotifyChat && value != "" {
+		err := m.checkOwnChat(c, value)
+		if err != nil {
+			return "", err
+		}
+	}
+	err := m.repo.SetUserSetting(c.userId, s.key, value)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "taskMng set setting: %w", err)
+	}
+	if value == "" {
+		return c.t(i18n.SettingCleared, s.key), nil
+	}
+	return fmt.Sprintf("%s = %s", s.key, value), nil
+}
+
+// checkOwnChat lets users send results only to their private chat or to
+// chats they sent commands from, admins may pick any chat.
+func (m *Mng) checkOwnChat(c call, value string) error {
+	if m.access.Grants(c.role, access.PermAdmin) {
+		return nil
+	}
+	chatId, _ := strconv.ParseInt(value, 10, 64)
+	// the private chat with a user has the id of the user
+	if chatId == c.userId {
+		return nil
+	}
+	dialogs, err := m.repo.ListUserDialogs(c.userId, historyScan)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng chats of %d: %w", c.userId, err)
+	}
+	for _, d := range dialogs {
+		for _, msg := range d.Messages {
+			if msg.Type == schema.MessageTypeUser && msg.UserId == c.userId && msg.ChatId == chatId {
+				return nil
+			}
+		}
+	}
+	return schema.Localized(schema.ErrCodeInvalidArgument, i18n.ForeignChat, value)
+}
+
+func unknownSetting(key string) error {
+	return schema.Localized(schema.ErrCodeInvalidArgument, i18n.UnknownSetting, key, strings.Join(settingKeys(), ", "))
+}
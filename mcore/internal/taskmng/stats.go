@@ -0,0 +1,143 @@
+package taskmng
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const statsDefaultDays = 7
+
+type statKey struct {
+	taskType schema.TaskType
+	command  string
+}
+
+type taskTrace struct {
+	key      statKey
+	created  time.Time
+	finished time.Time
+	failed   bool
+}
+
+// Stats sums up tasks finished since the given time from the event log.
+func (m *Mng) Stats(since time.Time) (schema.TaskStats, error) {
+	events, err := m.repo.ListTaskEvents(since)
+	if err != nil {
+		return schema.TaskStats{}, schema.Errorf(schema.ErrCodeStorageFailure, "stats: %w", err)
+	}
+
+	traces := map[int64]*taskTrace{}
+	for _, e := range events {
+		tr, ok := traces[e.TaskId]
+		if !ok {
+			tr = &taskTrace{key: statKey{taskType: e.TaskType, command: e.Command}}
+			traces[e.TaskId] = tr
+		}
+		switch e.Kind {
+		case schema.TaskEventCreated:
+			tr.created = e.CreatedAt
+		case schema.TaskEventDone, schema.TaskEventError:
+			// a retried task may fail and then succeed, the last answer counts
+			tr.finished = e.CreatedAt
+			tr.failed = e.Kind == schema.TaskEventError
+		}
+	}
+
+	byType := map[statKey][]*taskTrace{}
+	byCommand := map[statKey][]*taskTrace{}
+	for _, tr := range traces {
+		if tr.finished.IsZero() {
+			continue
+		}
+		typeKey := statKey{taskType: tr.key.taskType}
+		byType[typeKey] = append(byType[typeKey], tr)
+		byCommand[tr.key] = append(byCommand[tr.key], tr)
+	}
+
+	return schema.TaskStats{
+		Since:     since,
+		ByType:    sumStats(byType),
+		ByCommand: sumStats(byCommand),
+	}, nil
+}
+
+func sumStats(groups map[statKey][]*taskTrace) []schema.TaskStat {
+	ret := []schema.TaskStat{}
+	for key, traces := range groups {
+		st := schema.TaskStat{
+			TaskType: key.taskType,
+			Command:  key.command,
+			Finished: len(traces),
+		}
+		var durations []time.Duration
+		for _, tr := range traces {
+			if tr.failed {
+				st.Failed++
+			}
+			// tasks created before the window have no start
+			if !tr.created.IsZero() {
+				durations = append(durations, tr.finished.Sub(tr.created))
+			}
+		}
+		st.FailureRate = float64(st.Failed) / float64(st.Finished)
+		slices.Sort(durations)
+		st.P50 = percentile(durations, 50)
+		st.P90 = percentile(durations, 90)
+		st.P99 = percentile(durations, 99)
+		ret = append(ret, st)
+	}
+	slices.SortFunc(ret, func(a, b schema.TaskStat) int {
+		return cmp.Or(cmp.Compare(a.TaskType, b.TaskType), cmp.Compare(a.Command, b.Command))
+	})
+	return ret
+}
+
+// percentile takes the nearest rank of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// createStats answers /stats [days].
+func (m *Mng) createStats(c call) (string, error) {
+	days := statsDefaultDays
+	if c.arg("days") != "" {
+		days = c.intArg("days")
+	}
+	if days < 1 {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.StatsDays)
+	}
+
+	st, err := m.Stats(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return "", err
+	}
+	if len(st.ByType) == 0 {
+		return c.t(i18n.StatsNone, days), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(c.t(i18n.StatsTitle, days) + "\n")
+	for _, t := range st.ByType {
+		writeStat(&b, c, t.TaskType.String(), t)
+		for _, cmd := range st.ByCommand {
+			if cmd.TaskType == t.TaskType && cmd.Command != "" {
+				writeStat(&b, c, "  "+cmd.Command, cmd)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+func writeStat(b *strings.Builder, c call, name string, st schema.TaskStat) {
+	b.WriteString(c.t(i18n.StatsLine, name, st.Finished-st.Failed, st.Failed, st.FailureRate*100,
+		st.P50.Round(time.Second), st.P90.Round(time.Second), st.P99.Round(time.Second)) + "\n")
+}
@@ -0,0 +1,74 @@
+package taskmng
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{"empty", nil, 50, 0},
+		{"one", []time.Duration{7}, 99, 7},
+		{"p50", sorted, 50, 5},
+		{"p90", sorted, 90, 9},
+		{"p99", sorted, 99, 10},
+		{"p0", sorted, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%d) = %d, want %d", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateStats(t *testing.T) {
+	m := newTestMng(t, Config{})
+	start := time.Now().Add(-time.Hour)
+	for _, e := range []schema.TaskEvent{
+		{TaskId: 1, TaskType: schema.TaskTypeYtdl, Command: "y2a", Kind: schema.TaskEventCreated, CreatedAt: start},
+		{TaskId: 1, TaskType: schema.TaskTypeYtdl, Command: "y2a", Kind: schema.TaskEventDone, CreatedAt: start.Add(time.Minute)},
+		{TaskId: 2, TaskType: schema.TaskTypeYtdl, Command: "y2d", Kind: schema.TaskEventCreated, CreatedAt: start},
+		{TaskId: 2, TaskType: schema.TaskTypeYtdl, Command: "y2d", Kind: schema.TaskEventError, CreatedAt: start.Add(time.Second)},
+		// still running, not counted
+		{TaskId: 3, TaskType: schema.TaskTypeYtdl, Command: "y2d", Kind: schema.TaskEventCreated, CreatedAt: start},
+	} {
+		err := m.repo.AddTaskEvent(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := testCall(1, nil)
+	c.args["days"] = "1"
+	got, err := m.createStats(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"tasks for 1 days\n",
+		"ytdl: done 1, failed 1 (50%), p50 1s, p90 1m0s, p99 1m0s\n",
+		"  y2a: done 1, failed 0 (0%), p50 1m0s, p90 1m0s, p99 1m0s\n",
+		"  y2d: done 0, failed 1 (100%), p50 1s, p90 1s, p99 1s\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stats %q has no %q", got, want)
+		}
+	}
+
+	c.args["days"] = "0"
+	_, err = m.createStats(c)
+	if err == nil {
+		t.Error("want an error for 0 days")
+	}
+}
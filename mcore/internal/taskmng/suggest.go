@@ -0,0 +1,149 @@
+package taskmng
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// maxCallbackData is the limit of telegram on the data of a button.
+const maxCallbackData = 64
+
+// suggest fixes unknown command words of text with the closest names,
+// aliases and shortcuts the user may run, e.g. /torent ad m becomes
+// /torrent add m. ok is false when text has no unknown word or a word
+// is too far from all of them.
+func (r *registry) suggest(text string, allow allowFunc) (string, bool) {
+	l := newLexer(text)
+	word, ok, err := l.next()
+	if err != nil || !ok {
+		return "", false
+	}
+
+	changed := false
+	if findCommand(r.commands, word) == nil {
+		var candidates []string
+		for _, c := range r.commands {
+			candidates = append(candidates, visibleNames(c, allow)...)
+		}
+		for _, s := range r.shortcuts {
+			if cmd := r.lookup(strings.Fields(s.expands)); cmd != nil && cmd.allowed(allow) {
+				candidates = append(candidates, s.name)
+			}
+		}
+		name, ok := closest(normalize(word), candidates)
+		if !ok {
+			return "", false
+		}
+		// a shortcut stands for the beginning of its command
+		l = newLexer(r.expand(name + " " + l.text[l.pos:]))
+		word, _, _ = l.next()
+		changed = true
+	}
+	cmd := findCommand(r.commands, word)
+	fixed := []string{cmd.name}
+
+	for len(cmd.subs) > 0 {
+		word, ok, err = l.next()
+		if err != nil || !ok {
+			break
+		}
+		if normalize(word) == "help" {
+			fixed = append(fixed, "help")
+			break
+		}
+		sub := findCommand(cmd.subs, word)
+		if sub == nil {
+			var candidates []string
+			for _, s := range cmd.subs {
+				candidates = append(candidates, visibleNames(s, allow)...)
+			}
+			name, ok := closest(normalize(word), candidates)
+			if !ok {
+				return "", false
+			}
+			sub = findCommand(cmd.subs, name)
+			changed = true
+		}
+		cmd = sub
+		fixed = append(fixed, cmd.name)
+	}
+	if !changed {
+		return "", false
+	}
+	if rest := l.rest(); rest != "" {
+		fixed = append(fixed, rest)
+	}
+	return usagePath(fixed), true
+}
+
+// visibleNames returns the name and aliases of cmd when the user may see it.
+func visibleNames(cmd *command, allow allowFunc) []string {
+	if cmd.hidden || !cmd.allowed(allow) {
+		return nil
+	}
+	return append([]string{cmd.name}, cmd.aliases...)
+}
+
+// closest returns the candidate with the smallest edit distance to word,
+// the first one on a tie. A match must keep at least half of the word.
+func closest(word string, candidates []string) (string, bool) {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := distance(word, c)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if bestDist < 0 || bestDist > 2 || bestDist*2 > utf8.RuneCountInString(word) {
+		return "", false
+	}
+	return best, true
+}
+
+// distance is the Levenshtein distance of a and b in runes.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// suggestMsg answers an unknown command with the fixed command and a
+// button that runs it, ok is false when there is nothing to suggest.
+func (m *Mng) suggestMsg(text string, c call, err error) (schema.TaskMsg, bool) {
+	if schema.CodeOf(err) != schema.ErrCodeUnknownCommand {
+		return schema.TaskMsg{}, false
+	}
+	allow := func(p access.Perm) bool {
+		return m.access.Grants(c.role, p)
+	}
+	fixed, ok := m.commands.suggest(m.commands.expand(text), allow)
+	if !ok {
+		return schema.TaskMsg{}, false
+	}
+	msg := schema.TaskMsg{
+		Text: schema.Translate(err, c.lang).Error() + "\n" + c.t(i18n.DidYouMean, fixed),
+	}
+	if len(fixed) <= maxCallbackData {
+		msg.Buttons = [][]schema.Button{{{Text: fixed, Data: fixed}}}
+	}
+	return msg, true
+}
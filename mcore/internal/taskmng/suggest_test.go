@@ -0,0 +1,90 @@
+package taskmng
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"torrent", "torrent", 0},
+		{"torent", "torrent", 1},
+		{"trorent", "torrent", 2},
+		{"sttas", "stats", 2},
+		{"заметка", "заметки", 1},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"torrent", "t", "note", "n", "ds"}
+	tests := []struct {
+		word   string
+		want   string
+		wantOk bool
+	}{
+		{"torent", "torrent", true},
+		{"nite", "note", true},
+		// the first candidate wins a tie
+		{"dn", "n", true},
+		{"torrrrrent", "", false},
+		{"x", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := closest(tt.word, candidates)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("closest(%q) = %q, %v, want %q, %v", tt.word, got, ok, tt.want, tt.wantOk)
+		}
+	}
+	if _, ok := closest("torent", nil); ok {
+		t.Error("closest without candidates is ok")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	r := newRegistry()
+	guest := func(p access.Perm) bool { return p == access.PermBasic }
+	tests := []struct {
+		name   string
+		text   string
+		allow  allowFunc
+		want   string
+		wantOk bool
+	}{
+		{"command", "/torent add m", nil, "/torrent add m", true},
+		{"sub command", "/torrent ad m", nil, "/torrent add m", true},
+		{"both", "/torent ad m", nil, "/torrent add m", true},
+		{"nested sub", "/note inbx add buy milk", nil, "/note inbox add buy milk", true},
+		{"alias kept", "/t ad m", nil, "/torrent add m", true},
+		{"shortcut", "/dsll", nil, "/ds list", true},
+		{"help sub", "/torent help", nil, "/torrent help", true},
+		{"known command", "/torrent add m", nil, "", false},
+		{"too far", "/xyzzy", nil, "", false},
+		{"too far sub", "/torrent xyzzy", nil, "", false},
+		{"hidden command", "/fre", nil, "", false},
+		{"admin command", "/usres list", nil, "/users list", true},
+		{"not permitted", "/usres list", guest, "", false},
+		{"permitted", "/pnig", guest, "/ping", true},
+		{"empty", "", nil, "", false},
+		{"open quote", `"/torent`, nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := r.suggest(tt.text, tt.allow)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("suggest(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
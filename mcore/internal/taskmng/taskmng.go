@@ -1,28 +1,178 @@
 package taskmng
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/internal/storage"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
 type Mng struct {
-	repo repo
+	repo         repo
+	ttl          map[schema.TaskType]time.Duration
+	offlineAfter time.Duration
+	polls        *polls
+	commands     *registry
+	access       policy
+	userQuotas   map[schema.TaskType]int
+	concurrency  map[schema.TaskType]int
+	// types of tasks added in the transaction, nil outside of inTx
+	added *[]schema.TaskType
+}
+
+type Config struct {
+	ExpireInterval time.Duration `default:"1m" usage:"how often unclaimed tasks are expired"`
+	// ttl by task type name, e.g. torrent: 1h, a type without one never expires
+	Ttl map[string]time.Duration `usage:"a task not claimed within ttl expires"`
+	// a worker that did not ask for tasks for this long counts as offline, 0 turns the warning off
+	WorkerOfflineAfter time.Duration `default:"1m" usage:"warn on creation when the worker did not poll for this long"`
+	// open tasks by task type name one user may have, e.g. ytdl: 3
+	UserQuotas map[string]int `usage:"how many open tasks of a type one user may have"`
+	// open tasks by task type name of all users together, e.g. finance: 1
+	Concurrency map[string]int `usage:"how many open tasks of a type may be at once"`
 }
 
-func NewTaskMng(repo repo) *Mng {
-	return &Mng{
-		repo: repo,
+func NewTaskMng(repo repo, cfg Config, policy policy) (*Mng, error) {
+	m := &Mng{
+		repo:         repo,
+		access:       policy,
+		ttl:          map[schema.TaskType]time.Duration{},
+		offlineAfter: cfg.WorkerOfflineAfter,
+		polls:        &polls{last: map[schema.TaskType]time.Time{}},
+		commands:     newRegistry(),
+	}
+	for name, d := range cfg.Ttl {
+		t, ok := schema.ParseTaskType(name)
+		if !ok {
+			return nil, fmt.Errorf("taskMng unknown task type %s in ttl", name)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("taskMng ttl for %s must be positive", name)
+		}
+		m.ttl[t] = d
+	}
+	var err error
+	m.userQuotas, err = parseQuotas(cfg.UserQuotas)
+	if err != nil {
+		return nil, fmt.Errorf("taskMng user quotas: %w", err)
+	}
+	m.concurrency, err = parseQuotas(cfg.Concurrency)
+	if err != nil {
+		return nil, fmt.Errorf("taskMng concurrency: %w", err)
 	}
+	for path, p := range policy.Commands() {
+		cmd := m.commands.lookup(strings.Fields(path))
+		if cmd == nil {
+			return nil, fmt.Errorf("taskMng unknown command %s in access", path)
+		}
+		cmd.setPerm(p)
+	}
+	return m, nil
+}
+
+type policy interface {
+	Role(userId int64, userName string) (string, error)
+	Grants(role string, p access.Perm) bool
+	HasRole(role string) bool
+	Roles() []string
+	NamedUsers() map[string]string
+	AdminChatId() int64
+	Commands() map[string]access.Perm
 }
 
 type repo interface {
 	AddTask(task schema.Task) (int64, error)
-	GetFirstTaskByType(t schema.TaskType) (schema.Task, error)
+	ClaimTask(t schema.TaskType) (schema.Task, error)
 	GetTaskById(id int64) (schema.Task, error)
 	GetDialogById(id int64) (schema.Dialog, error)
 	UpdateTaskStatus(task schema.Task) error
 	UpdateDialog(d schema.Dialog) error
+	AddDialogRef(chatId int64, messageId int, dialogId int64) error
+	AddTaskEvent(e schema.TaskEvent) error
+	ListTaskEvents(since time.Time) ([]schema.TaskEvent, error)
+	ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error)
+	ListUserTasks(userId int64, status schema.TaskStatus) ([]schema.Task, error)
+	CountOpenTasks(taskType schema.TaskType, userId int64) (all, mine int, err error)
+	ListDialogTasks(dialogId int64) ([]schema.Task, error)
+	ListUserDialogs(userId int64, limit int) ([]schema.Dialog, error)
+	AddUser(u schema.User) error
+	GetUser(id int64) (schema.User, error)
+	UpdateUser(u schema.User) error
+	ListUsers() ([]schema.User, error)
+	SetUserSetting(userId int64, key, value string) error
+	ListUserSettings(userId int64) (schema.Settings, error)
+	SetUserAlias(userId int64, name, expansion string) error
+	ListUserAliases(userId int64) (schema.Aliases, error)
+	InTx(fn func(tx storage.Repo) error) error
+}
+
+// inTx runs fn with a copy of the manager bound to one transaction, inside
+// a transaction fn joins it.
+func (m *Mng) inTx(fn func(m *Mng) error) error {
+	if m.added != nil {
+		return fn(m)
+	}
+	return m.repo.InTx(func(tx storage.Repo) error {
+		txMng := *m
+		txMng.repo = storage.TxRepo{Repo: tx}
+		txMng.added = new([]schema.TaskType)
+		return fn(&txMng)
+	})
 }
 
-func (m *Mng) GetTask(taskType schema.TaskType) (schema.Task, error) {
-	return m.repo.GetFirstTaskByType(taskType)
+// GetTask gives the oldest created task of taskType to worker.
+func (m *Mng) GetTask(taskType schema.TaskType, worker string) (schema.Task, error) {
+	if taskType == schema.TaskTypeUndefined {
+		return schema.Task{}, schema.Errorf(schema.ErrCodeInvalidArgument, "getTask wrong task type")
+	}
+	m.polls.seen(taskType)
+
+	var task schema.Task
+	err := m.inTx(func(m *Mng) error {
+		var err error
+		task, err = m.repo.ClaimTask(taskType)
+		if err != nil || task.Id == 0 {
+			return err
+		}
+		return m.addEvent(task, schema.TaskEventClaimed, worker, "")
+	})
+	if err != nil {
+		return schema.Task{}, schema.Errorf(schema.ErrCodeStorageFailure, "getTask: %w", err)
+	}
+	return task, nil
+}
+
+// addTask saves a new task and opens its event log.
+func (m *Mng) addTask(task schema.Task) (int64, error) {
+	id, err := m.repo.AddTask(task)
+	if err != nil {
+		return 0, err
+	}
+	task.Id = id
+	err = m.addEvent(task, schema.TaskEventCreated, "", "")
+	if err != nil {
+		return 0, err
+	}
+	if m.added != nil {
+		*m.added = append(*m.added, task.Type)
+	}
+	return id, nil
+}
+
+func (m *Mng) addEvent(task schema.Task, kind schema.TaskEventKind, worker string, msg string) error {
+	err := m.repo.AddTaskEvent(schema.TaskEvent{
+		TaskId:   task.Id,
+		TaskType: task.Type,
+		Command:  task.Command(),
+		Kind:     kind,
+		Worker:   worker,
+		Message:  msg,
+	})
+	if err != nil {
+		return fmt.Errorf("task %d event %s: %w", task.Id, kind, err)
+	}
+	return nil
 }
@@ -0,0 +1,33 @@
+package taskmng
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/internal/storage/memstore"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// newTestMng is a manager on an empty memstore with cfg.
+func newTestMng(t *testing.T, cfg Config) *Mng {
+	t.Helper()
+	db := memstore.NewMemStore()
+	policy, err := access.New(access.Config{}, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewTaskMng(db, cfg, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// testCall is a call of an admin with settings.
+func testCall(userId int64, settings schema.Settings) call {
+	if settings == nil {
+		settings = schema.Settings{}
+	}
+	return call{userId: userId, role: access.RoleAdmin, settings: settings, lang: i18n.En, args: map[string]string{}}
+}
@@ -0,0 +1,85 @@
+package taskmng
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// lexer splits a message into tokens on demand. Any run of spaces, tabs and
+// newlines separates tokens, a token starting with a quote lasts until the
+// closing quote. Free text arguments take the raw rest, so quotes and
+// apostrophes inside them are never parsed.
+type lexer struct {
+	text string
+	pos  int
+}
+
+func newLexer(text string) *lexer {
+	return &lexer{text: text}
+}
+
+// next returns the next token, ok is false at the end of the text.
+func (l *lexer) next() (string, bool, error) {
+	l.skipSpaces()
+	if l.pos >= len(l.text) {
+		return "", false, nil
+	}
+
+	q := l.text[l.pos]
+	if q == '"' || q == '\'' {
+		return l.quoted(q)
+	}
+
+	start := l.pos
+	for l.pos < len(l.text) {
+		r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	return l.text[start:l.pos], true, nil
+}
+
+// quoted reads a token in quotes, a backslash escapes the next char
+// inside double quotes.
+func (l *lexer) quoted(q byte) (string, bool, error) {
+	var b strings.Builder
+	l.pos++
+	for l.pos < len(l.text) {
+		c := l.text[l.pos]
+		switch {
+		case c == q:
+			l.pos++
+			return b.String(), true, nil
+		case c == '\\' && q == '"' && l.pos+1 < len(l.text):
+			b.WriteByte(l.text[l.pos+1])
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return "", false, schema.Localized(schema.ErrCodeInvalidArgument, i18n.QuoteNotClosed, q)
+}
+
+// rest returns the untouched remainder of the text without outer spaces.
+func (l *lexer) rest() string {
+	r := strings.TrimSpace(l.text[l.pos:])
+	l.pos = len(l.text)
+	return r
+}
+
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.text) {
+		r, size := utf8.DecodeRuneInString(l.text[l.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		l.pos += size
+	}
+}
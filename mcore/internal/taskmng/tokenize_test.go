@@ -0,0 +1,88 @@
+package taskmng
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"spaces only", " \t\n ", nil, false},
+		{"words", "/note inbox add", []string{"/note", "inbox", "add"}, false},
+		{"runs of spaces", "  a\t\tb \n c  ", []string{"a", "b", "c"}, false},
+		{"unicode spaces", "a\u00a0b\u2003c", []string{"a", "b", "c"}, false},
+		{"cyrillic", "/заметка привет", []string{"/заметка", "привет"}, false},
+		{"double quotes", `add "two words" x`, []string{"add", "two words", "x"}, false},
+		{"single quotes", `add 'it is' x`, []string{"add", "it is", "x"}, false},
+		{"empty quotes", `a "" b`, []string{"a", "", "b"}, false},
+		{"escaped quote", `"say \"hi\""`, []string{`say "hi"`}, false},
+		{"escaped backslash", `"a\\b"`, []string{`a\b`}, false},
+		{"no escape in single quotes", `'a\'`, []string{`a\`}, false},
+		{"quote inside a word", `it's fine`, []string{"it's", "fine"}, false},
+		{"quote ends a token", `"a"b`, []string{"a", "b"}, false},
+		{"open double quote", `add "never closed`, []string{"add"}, true},
+		{"open single quote", `add 'x`, []string{"add"}, true},
+		{"trailing backslash", `"a\`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLexer(tt.text)
+			var got []string
+			for {
+				tok, ok, err := l.next()
+				if err != nil {
+					if !tt.wantErr {
+						t.Fatalf("next: %v", err)
+					}
+					break
+				}
+				if !ok {
+					if tt.wantErr {
+						t.Fatal("want an error")
+					}
+					break
+				}
+				got = append(got, tok)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tokens = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexerRest(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		words int
+		want  string
+	}{
+		{"all", `  it's "raw"  `, 0, `it's "raw"`},
+		{"after words", `/note add  buy "milk" 'n bread `, 2, `buy "milk" 'n bread`},
+		{"nothing left", "/note add", 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLexer(tt.text)
+			for range tt.words {
+				_, _, err := l.next()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := l.rest(); got != tt.want {
+				t.Errorf("rest = %q, want %q", got, tt.want)
+			}
+			if _, ok, _ := l.next(); ok {
+				t.Error("rest left tokens")
+			}
+		})
+	}
+}
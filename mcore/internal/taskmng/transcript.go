@@ -0,0 +1,68 @@
+package taskmng
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// appendBot adds a message of the bot sent to chatId to the transcript of
+// the dialog, taskId names the task the message reports on. The caller
+// saves the dialog.
+func appendBot(dialog *schema.Dialog, chatId int64, text string, taskId int64) {
+	if text == "" {
+		return
+	}
+	dialog.Messages = append(dialog.Messages, schema.Message{
+		ChatId:    chatId,
+		Text:      text,
+		Type:      schema.MessageTypeBot,
+		CreatedAt: time.Now(),
+		TaskId:    taskId,
+	})
+}
+
+// record saves the direct reply of the bot in the transcript of the dialog.
+func (m *Mng) record(dialog *schema.Dialog, chatId int64, text string) error {
+	appendBot(dialog, chatId, text, 0)
+	err := m.repo.UpdateDialog(*dialog)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "taskMng record reply: %w", err)
+	}
+	return nil
+}
+
+// changeDialog reads the dialog in a transaction, applies change and saves
+// it, messages other writers add to the transcript meanwhile are kept.
+func (m *Mng) changeDialog(dialogId int64, change func(dialog *schema.Dialog)) error {
+	return m.inTx(func(m *Mng) error {
+		dialog, err := m.repo.GetDialogById(dialogId)
+		if err != nil {
+			return fmt.Errorf("get dialog %d: %w", dialogId, err)
+		}
+		change(&dialog)
+		return m.repo.UpdateDialog(dialog)
+	})
+}
+
+// errorReply is the text the user reads for err, tbot hides errors that
+// are not of the user.
+func errorReply(err error, lang i18n.Lang) string {
+	if !schema.IsUserError(err) {
+		return i18n.T(lang, i18n.SomethingWrong)
+	}
+	return schema.Translate(err, lang).Error()
+}
+
+// lastUserMessage is the message of the user a reply of the dialog answers.
+func lastUserMessage(dialog schema.Dialog) schema.Message {
+	last := dialog.Messages[0]
+	for _, msg := range dialog.Messages {
+		if msg.Type == schema.MessageTypeUser {
+			last = msg
+		}
+	}
+	return last
+}
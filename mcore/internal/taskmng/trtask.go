@@ -1,84 +1,48 @@
 package taskmng
 
 import (
-	"fmt"
-	"strconv"
-	"strings"
-
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 )
 
-func (m *Mng) createTrTask(dialogId int64, text string, torrentUrl string) (string, error) {
-	w := strings.Split(text, " ")
-	if len(w) < 2 {
-		return "", fmt.Errorf("for tr need command")
-	}
-	var folderPath string
-	var torrentId int
-	var err error
-	var command string
-
-	if w[1] == "add" {
-		if len(w) < 3 {
-			return "", fmt.Errorf("for tr add need label")
-		}
-		folderPath, err = chooseFolderPath(w[2])
-		if err != nil {
-			return "", err
-		}
-
-		if len(torrentUrl) == 0 {
-			return "", fmt.Errorf("for tr add need torrent url")
-		}
-		command = w[1]
-	}
-
-	if w[1] == "del" {
-		if len(w) < 3 {
-			return "", fmt.Errorf("for tr del need id")
-		}
-		torrentId, err = strconv.Atoi(w[2])
-		if err != nil {
-			return "", fmt.Errorf("for tr del id is not an int")
-		}
-		command = w[1]
-	}
-
-	if w[1] == "list" {
-		if len(w) != 2 {
-			return "", fmt.Errorf("list command does not have arguments")
-		}
-
-		command = w[1]
-	}
-
-	if w[1] == "help" {
-		return trHelpText, nil
-	}
-	if command == "" {
-		return "", fmt.Errorf("command not found")
-	}
-
+func (m *Mng) createTrTask(c call) (string, error) {
+	command := c.path[len(c.path)-1]
 	task := schema.Task{
-		DialogId: dialogId,
+		DialogId: c.dialogId,
 		Type:     schema.TaskTypeTorrent,
 		Status:   schema.TaskStatusCreate,
 		TaskData: schema.TaskData{
 			Tr: schema.TaskTr{
-				FolderPath: folderPath,
-				TorrentUrl: torrentUrl,
-				TorrentId:  torrentId,
-				Command:    command,
+				Command: command,
 			},
 		},
 	}
 
-	_, err = m.repo.AddTask(task)
+	switch command {
+	case "add":
+		folderPath, err := chooseFolderPath(c.arg("category"))
+		if err != nil {
+			return "", err
+		}
+		torrentUrl := c.arg("url")
+		if torrentUrl == "" {
+			torrentUrl = c.fileUrl
+		}
+		if torrentUrl == "" {
+			return "", &ask{arg: "url", question: c.t(i18n.SendTorrent)}
+		}
+		task.TaskData.Tr.FolderPath = folderPath
+		task.TaskData.Tr.TorrentUrl = torrentUrl
+	case "del":
+		task.TaskData.Tr.TorrentId = c.intArg("id")
+	}
+
+	err := m.addUserTask(c, task)
 	if err != nil {
-		return "", fmt.Errorf("taskMng add task: %w", err)
+		return "", err
 	}
 
-	return "task tr created", nil
+	return c.t(i18n.TaskCreated, "tr"), nil
 }
 
 func chooseFolderPath(label string) (string, error) {
@@ -97,5 +61,5 @@ func chooseFolderPath(label string) (string, error) {
 		return "cartoon_s", nil
 	}
 
-	return "", fmt.Errorf("wrong label")
+	return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.UnknownCategory, label)
 }
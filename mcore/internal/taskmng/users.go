@@ -0,0 +1,220 @@
+package taskmng
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// RequestAccess saves the request of a user without a role and asks
+// the admin chat to approve it with buttons that run /users commands.
+func (m *Mng) RequestAccess(msg schema.Message) (string, error) {
+	lang := m.Lang(msg)
+	var reply string
+	err := m.inTx(func(m *Mng) error {
+		u, err := m.repo.GetUser(msg.UserId)
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "requestAccess get user: %w", err)
+		}
+		switch u.Status {
+		case schema.UserStatusRequested:
+			reply = i18n.T(lang, i18n.AccessWaiting)
+			return nil
+		case schema.UserStatusDenied, schema.UserStatusRevoked:
+			return schema.Localized(schema.ErrCodeUnauthorizedUser, i18n.AccessClosed, userTitle(u), statusText(lang, u.Status))
+		}
+
+		// an active user without a role asks again, the row is already there
+		known := u.Id != 0
+		u = schema.User{Id: msg.UserId, UserName: msg.UserName, ChatId: msg.ChatId, Status: schema.UserStatusRequested}
+		if known {
+			err = m.repo.UpdateUser(u)
+		} else {
+			err = m.repo.AddUser(u)
+		}
+		if err != nil {
+			return schema.Errorf(schema.ErrCodeStorageFailure, "requestAccess save user: %w", err)
+		}
+		reply = i18n.T(lang, i18n.AccessRequested)
+
+		chatId := m.access.AdminChatId()
+		if chatId == 0 {
+			return nil
+		}
+		var buttons []schema.Button
+		for _, role := range m.access.Roles() {
+			if role == access.RoleAdmin {
+				continue
+			}
+			buttons = append(buttons, schema.Button{Text: role, Data: fmt.Sprintf("/users approve %d %s", u.Id, role)})
+		}
+		buttons = append(buttons, schema.Button{Text: i18n.T(i18n.Default, i18n.Deny), Data: fmt.Sprintf("/users deny %d", u.Id)})
+		return m.NotifyMsg(schema.TaskMsg{
+			ChatId:  chatId,
+			Text:    i18n.T(i18n.Default, i18n.AccessRequest, userTitle(u)),
+			Buttons: [][]schema.Button{buttons},
+		})
+	})
+	return reply, err
+}
+
+func (m *Mng) listUsers(c call) (string, error) {
+	users, err := m.repo.ListUsers()
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "listUsers: %w", err)
+	}
+	named := m.access.NamedUsers()
+	if len(users) == 0 && len(named) == 0 {
+		return c.t(i18n.UsersNone), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(c.t(i18n.UsersTitle))
+	for _, u := range users {
+		if u.Status == schema.UserStatusActive {
+			fmt.Fprintf(&b, "\n- %s %s", userTitle(u), u.Role)
+		} else {
+			fmt.Fprintf(&b, "\n- %s %s", userTitle(u), statusText(c.lang, u.Status))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		b.WriteString("\n- " + c.t(i18n.UserByName, name, named[name]))
+	}
+	return b.String(), nil
+}
+
+func (m *Mng) approveUser(c call) (string, error) {
+	role := c.arg("role")
+	if role == "" {
+		role = access.RoleGuest
+	}
+	u, err := m.userArg(c, role)
+	if err != nil {
+		return "", err
+	}
+	if u.Status != schema.UserStatusRequested && u.Status != schema.UserStatusDenied {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.UserNotPending, userTitle(u), statusText(c.lang, u.Status))
+	}
+	u.Role = role
+	u.Status = schema.UserStatusActive
+	err = m.saveUser(u, i18n.AccessApproved, role)
+	if err != nil {
+		return "", err
+	}
+	return c.t(i18n.UserApproved, userTitle(u), role), nil
+}
+
+func (m *Mng) denyUser(c call) (string, error) {
+	u, err := m.userArg(c, "")
+	if err != nil {
+		return "", err
+	}
+	if u.Status != schema.UserStatusRequested {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.UserNotDeniable, userTitle(u), statusText(c.lang, u.Status))
+	}
+	u.Status = schema.UserStatusDenied
+	err = m.saveUser(u, i18n.AccessDenied)
+	if err != nil {
+		return "", err
+	}
+	return c.t(i18n.UserDenied, userTitle(u)), nil
+}
+
+func (m *Mng) revokeUser(c call) (string, error) {
+	u, err := m.userArg(c, "")
+	if err != nil {
+		return "", err
+	}
+	if u.Id == c.userId {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.RevokeSelf)
+	}
+	if u.Status != schema.UserStatusActive {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.UserNotActive, userTitle(u), statusText(c.lang, u.Status))
+	}
+	u.Status = schema.UserStatusRevoked
+	err = m.saveUser(u, "")
+	if err != nil {
+		return "", err
+	}
+	return c.t(i18n.UserRevoked, userTitle(u)), nil
+}
+
+// setUserRole gives the user a role and activates it, a user the bot
+// never saw is added.
+func (m *Mng) setUserRole(c call) (string, error) {
+	id := int64(c.intArg("id"))
+	role := c.arg("role")
+	if !m.access.HasRole(role) {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.UnknownRole, role, strings.Join(m.access.Roles(), ", "))
+	}
+	u, err := m.repo.GetUser(id)
+	if err != nil {
+		return "", schema.Errorf(schema.ErrCodeStorageFailure, "setUserRole get user: %w", err)
+	}
+	if u.Id == 0 {
+		err = m.repo.AddUser(schema.User{Id: id, Role: role, Status: schema.UserStatusActive})
+		if err != nil {
+			return "", schema.Errorf(schema.ErrCodeStorageFailure, "setUserRole add user: %w", err)
+		}
+		return c.t(i18n.UserAdded, id, role), nil
+	}
+
+	u.Role = role
+	u.Status = schema.UserStatusActive
+	err = m.saveUser(u, "")
+	if err != nil {
+		return "", err
+	}
+	return c.t(i18n.UserRoleSet, userTitle(u), role), nil
+}
+
+// userArg loads the user of the id arg, role is checked when it is set.
+func (m *Mng) userArg(c call, role string) (schema.User, error) {
+	if role != "" && !m.access.HasRole(role) {
+		return schema.User{}, schema.Localized(schema.ErrCodeInvalidArgument, i18n.UnknownRole, role, strings.Join(m.access.Roles(), ", "))
+	}
+	id := int64(c.intArg("id"))
+	u, err := m.repo.GetUser(id)
+	if err != nil {
+		return schema.User{}, schema.Errorf(schema.ErrCodeStorageFailure, "get user %d: %w", id, err)
+	}
+	if u.Id == 0 {
+		return schema.User{}, schema.Localized(schema.ErrCodeInvalidArgument, i18n.UserNotFound, id)
+	}
+	return u, nil
+}
+
+// saveUser updates the user and tells them the text of key in their
+// language when the key is set and their chat is known.
+func (m *Mng) saveUser(u schema.User, key i18n.Key, args ...any) error {
+	err := m.repo.UpdateUser(u)
+	if err != nil {
+		return schema.Errorf(schema.ErrCodeStorageFailure, "update user %d: %w", u.Id, err)
+	}
+	if key == "" || u.ChatId == 0 {
+		return nil
+	}
+	settings, err := m.userSettings(u.Id)
+	if err != nil {
+		return err
+	}
+	return m.Notify(u.ChatId, 0, i18n.T(langOf(settings, ""), key, args...))
+}
+
+func statusText(lang i18n.Lang, status schema.UserStatus) string {
+	return i18n.T(lang, i18n.Status(status.String()))
+}
+
+func userTitle(u schema.User) string {
+	id := strconv.FormatInt(u.Id, 10)
+	if u.UserName == "" {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", u.UserName, id)
+}
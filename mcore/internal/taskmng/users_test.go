@@ -0,0 +1,48 @@
+package taskmng
+
+import (
+	"testing"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestRequestAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    schema.User
+		wantErr bool
+	}{
+		{"stranger", schema.User{}, false},
+		{"active without role", schema.User{Id: 7, ChatId: 1, Status: schema.UserStatusActive}, false},
+		{"requested", schema.User{Id: 7, ChatId: 1, Status: schema.UserStatusRequested}, false},
+		{"denied", schema.User{Id: 7, ChatId: 1, Status: schema.UserStatusDenied}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMng(t, Config{})
+			if tt.user.Id != 0 {
+				err := m.repo.AddUser(tt.user)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := m.RequestAccess(schema.Message{UserId: 7, UserName: "kid", ChatId: 7})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := m.repo.GetUser(7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Status != schema.UserStatusRequested {
+				t.Errorf("user status = %s, want requested", u.Status)
+			}
+		})
+	}
+}
@@ -1,41 +1,44 @@
 package taskmng
 
 import (
-	"fmt"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 	"net/url"
-	"strings"
 )
 
-func (m *Mng) createYtdlTask(dialogId int64, userName string, text string) (string, error) {
-	w := strings.Split(text, " ")
-	if len(w) < 2 {
-		return "", fmt.Errorf("for y2d need a link")
-	}
-	u, err := url.Parse(w[1])
+func (m *Mng) createYtdlTask(c call) (string, error) {
+	u, err := url.Parse(c.arg("link"))
 	if err != nil {
-		return "", fmt.Errorf("can't parse url %w", err)
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.BadUrl, c.arg("link"))
 	}
 
-	if u.Host != "youtube.com" && u.Host != "www.youtube.com" && u.Host != "youtu.be" {
-		return "", fmt.Errorf("host: %s not yuotube", u.Host)
+	if !isYoutube(u.Host) {
+		return "", schema.Localized(schema.ErrCodeInvalidArgument, i18n.NotYoutube, u.Host)
 	}
 
 	task := schema.Task{
-		DialogId: dialogId,
+		DialogId: c.dialogId,
 		Type:     schema.TaskTypeYtdl,
 		Status:   schema.TaskStatusCreate,
 		TaskData: schema.TaskData{
 			Ytdl: schema.TaskYtdl{
-				Link:     w[1],
-				UserName: userName,
+				Link:     c.arg("link"),
+				UserName: c.userName,
 			},
 		},
 	}
 
-	_, err = m.repo.AddTask(task)
+	err = m.addUserTask(c, task)
 	if err != nil {
-		return "", fmt.Errorf("taskMng add task: %w", err)
+		return "", err
+	}
+	return c.t(i18n.TaskCreated, "ytd"), nil
+}
+
+func isYoutube(host string) bool {
+	switch host {
+	case "youtube.com", "www.youtube.com", "m.youtube.com", "youtu.be":
+		return true
 	}
-	return "task ytd created", nil
+	return false
 }
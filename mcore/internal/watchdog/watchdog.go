@@ -0,0 +1,199 @@
+// Package watchdog alerts the admin chat about tasks stuck in create or
+// sended longer than allowed and tells the requester about the delay.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+	"github.com/ishua/a3bot6/mcore/pkg/logger"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+type Config struct {
+	Interval time.Duration `default:"1m" usage:"how often the watchdog checks tasks"`
+	// thresholds by task type name, e.g. ytdl: 10m, a type without one is not watched
+	CreateAfter map[string]time.Duration `usage:"alert when a task waits in create longer"`
+	SendedAfter map[string]time.Duration `usage:"alert when a task is in sended longer"`
+}
+
+type Watchdog struct {
+	repo        repo
+	notifier    notifier
+	adminChatId int64
+	thresholds  map[schema.TaskStatus]map[schema.TaskType]time.Duration
+}
+
+type repo interface {
+	ListTasksByStatus(status schema.TaskStatus) ([]schema.Task, error)
+	GetTaskById(id int64) (schema.Task, error)
+	AddTaskAlert(a schema.TaskAlert) error
+	ListTaskAlerts() ([]schema.TaskAlert, error)
+	DeleteTaskAlert(taskId int64, status schema.TaskStatus) error
+}
+
+type notifier interface {
+	Notify(chatId int64, replyMessageId int, text string) error
+	// NotifyDialog tells the text of key to the user of the dialog in their language
+	NotifyDialog(dialogId, taskId int64, key i18n.Key, args ...any) error
+}
+
+// New alerts adminChatId, the access admin chat, alerts are only logged when it is 0
+func New(cfg Config, adminChatId int64, repo repo, notifier notifier) (*Watchdog, error) {
+	w := &Watchdog{
+		repo:        repo,
+		notifier:    notifier,
+		adminChatId: adminChatId,
+		thresholds:  map[schema.TaskStatus]map[schema.TaskType]time.Duration{},
+	}
+	for status, byName := range map[schema.TaskStatus]map[string]time.Duration{
+		schema.TaskStatusCreate: cfg.CreateAfter,
+		schema.TaskStatusSended: cfg.SendedAfter,
+	} {
+		w.thresholds[status] = map[schema.TaskType]time.Duration{}
+		for name, d := range byName {
+			t, ok := schema.ParseTaskType(name)
+			if !ok {
+				return nil, fmt.Errorf("watchdog unknown task type %s", name)
+			}
+			if d <= 0 {
+				return nil, fmt.Errorf("watchdog threshold for %s must be positive", name)
+			}
+			w.thresholds[status][t] = d
+		}
+	}
+	return w, nil
+}
+
+// Enabled is false when no threshold is configured.
+func (w *Watchdog) Enabled() bool {
+	return len(w.thresholds[schema.TaskStatusCreate])+len(w.thresholds[schema.TaskStatusSended]) > 0
+}
+
+// Check sends recovery notices for alerted tasks that moved on, then alerts
+// about tasks over their threshold. A task is alerted once per status.
+func (w *Watchdog) Check(_ context.Context) error {
+	alerts, err := w.repo.ListTaskAlerts()
+	if err != nil {
+		return fmt.Errorf("watchdog list alerts: %w", err)
+	}
+	alerted := map[alertKey]bool{}
+	for _, a := range alerts {
+		recovered, err := w.recover(a)
+		if err != nil {
+			return err
+		}
+		if !recovered {
+			alerted[alertKey{taskId: a.TaskId, status: a.Status}] = true
+		}
+	}
+
+	now := time.Now()
+	for status, byType := range w.thresholds {
+		if len(byType) == 0 {
+			continue
+		}
+		tasks, err := w.repo.ListTasksByStatus(status)
+		if err != nil {
+			return fmt.Errorf("watchdog list tasks: %w", err)
+		}
+		for _, task := range tasks {
+			limit, ok := byType[task.Type]
+			// notices have no dialog, alerting about them would feed a loop of new notices
+			if !ok || task.DialogId == 0 || alerted[alertKey{taskId: task.Id, status: status}] {
+				continue
+			}
+			age := now.Sub(since(task))
+			if age < limit {
+				continue
+			}
+			err = w.alert(task, age)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type alertKey struct {
+	taskId int64
+	status schema.TaskStatus
+}
+
+// since is when the task entered its status, a claim or a progress report
+// of a worker moves updated_at.
+func since(task schema.Task) time.Time {
+	if task.Status == schema.TaskStatusCreate {
+		return task.CreatedAt
+	}
+	return task.UpdatedAt
+}
+
+func (w *Watchdog) alert(task schema.Task, age time.Duration) error {
+	// the alert row is saved first, so a failing notice is not repeated every tick
+	err := w.repo.AddTaskAlert(schema.TaskAlert{TaskId: task.Id, Status: task.Status})
+	if err != nil {
+		return fmt.Errorf("watchdog add alert: %w", err)
+	}
+
+	err = w.toAdmin(i18n.AlertStuck, task.Id, task.Type, task.Command(), statusText(task.Status), age.Round(time.Minute))
+	if err != nil {
+		return err
+	}
+
+	// a stuck bot message can not reach the requester anyway
+	if task.Type == schema.TaskTypeMsg {
+		return nil
+	}
+	key := i18n.TaskWaiting
+	if task.Status == schema.TaskStatusSended {
+		key = i18n.TaskSlow
+	}
+	err = w.notifier.NotifyDialog(task.DialogId, task.Id, key, task.Type)
+	if err != nil {
+		return fmt.Errorf("watchdog notify user: %w", err)
+	}
+	return nil
+}
+
+func (w *Watchdog) recover(a schema.TaskAlert) (bool, error) {
+	task, err := w.repo.GetTaskById(a.TaskId)
+	if err != nil {
+		return false, fmt.Errorf("watchdog get task %d: %w", a.TaskId, err)
+	}
+	if task.Id != 0 && task.Status == a.Status {
+		return false, nil
+	}
+
+	err = w.repo.DeleteTaskAlert(a.TaskId, a.Status)
+	if err != nil {
+		return false, fmt.Errorf("watchdog delete alert: %w", err)
+	}
+	if task.Id == 0 {
+		return true, nil
+	}
+	return true, w.toAdmin(i18n.AlertRecovered, task.Id, task.Type, task.Command(),
+		statusText(task.Status), time.Since(a.CreatedAt).Round(time.Minute))
+}
+
+// toAdmin sends the text of key to the admin chat, admins read the
+// default language.
+func (w *Watchdog) toAdmin(key i18n.Key, args ...any) error {
+	text := i18n.T(i18n.Default, key, args...)
+	if w.adminChatId == 0 {
+		logger.Info("watchdog: " + text)
+		return nil
+	}
+	err := w.notifier.Notify(w.adminChatId, 0, text)
+	if err != nil {
+		return fmt.Errorf("watchdog notify admin: %w", err)
+	}
+	return nil
+}
+
+func statusText(status schema.TaskStatus) string {
+	return i18n.T(i18n.Default, i18n.Status(status.String()))
+}
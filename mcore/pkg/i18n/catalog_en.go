@@ -0,0 +1,136 @@
+package i18n
+
+// en is the default catalog, summaries of commands and questions of
+// their args are written in english next to the commands.
+var en = map[Key]string{
+	NotAnswering:     "I don't answer to user %s",
+	QuestionAnswered: "this question is already answered",
+	MessageEmpty:     "message is empty",
+	QuoteNotClosed:   "quote %c is not closed",
+	CommandNotFound:  "command not found: %s",
+	NeedSubcommand:   "for %s need command\n%s",
+	UnknownSub:       "unknown command %s for %s",
+	NeedArg:          "for %s need %s, usage: %s",
+	NotNumber:        "%s of %s is not a number",
+	TooManyArgs:      "too many arguments, usage: %s",
+	NotPermitted:     "not permitted: %s needs %s",
+	ReplyHint:        "reply to this message, cancel to stop",
+	Cancelled:        "ok, cancelled",
+	DidYouMean:       "did you mean %s?",
+	IntentYoutube:    "download this video from youtube?",
+	IntentTorrent:    "add this torrent to Download Station?",
+	IntentInbox:      "add this text to the notes inbox?",
+
+	HelpTitle:     "My commands:",
+	HelpMore:      "/help <command> tells more",
+	HelpAliases:   "aliases: %s",
+	HelpShortcuts: "shortcuts: %s",
+	NoHelp:        "no help for %s",
+
+	TaskCreated:     "task %s created",
+	HealthCreated:   "tasks health created",
+	NotDoneYet:      "need todo",
+	WorkerOffline:   "no worker online for %s, it will run when one connects",
+	TaskExpired:     "your %s task expired, no worker took it in %s, try again later",
+	TaskWaiting:     "your %s task is delayed, it waits for a worker",
+	TaskSlow:        "your %s task is delayed, the worker takes longer than usual",
+	AlertStuck:      "alert: task #%d %s %s is %s for %s",
+	AlertRecovered:  "recovered: task #%d %s %s is %s now, alerted %s ago",
+	SendTorrent:     "send a torrent link or file",
+	ConfirmDelete:   "delete download %s?",
+	DownloadKept:    "ok, download is kept",
+	UnknownCategory: "unknown category: %s",
+	BadUrl:          "can't parse url %s",
+	NotYoutube:      "host %s is not youtube",
+	StatsDays:       "stats days must be a positive number",
+	StatsNone:       "no finished tasks for %d days",
+	StatsTitle:      "tasks for %d days",
+	StatsLine:       "%s: done %d, failed %d (%.0f%%), p50 %s, p90 %s, p99 %s",
+	Yes:             "yes",
+	No:              "no",
+
+	SettingsTitle:   "settings:",
+	SettingNotSet:   "%s is not set, %s",
+	SettingsNeedId:  "settings need your telegram id, tbot sends it",
+	SettingCleared:  "%s is cleared",
+	UnknownSetting:  "unknown setting %s, settings: %s",
+	WrongSetting:    "%s: %s is not valid, %s",
+	ForeignChat:     "chat %s is not yours, send a command from it first",
+	SettingTimezone: "time zone like Europe/Moscow, notes are dated in it",
+	SettingLanguage: "language of replies: %s",
+	SettingCategory: "category of /ds add when none is given",
+	SettingQuiet:    "hours like 23-7 when results come without a sound",
+	SettingNotify:   "id of one of your chats for results of tasks instead of the chat of the command",
+	SettingIntents:  "links, torrents and text without a command: confirm asks first, auto runs the guess, off does nothing",
+
+	AliasesTitle:  "aliases:",
+	AliasesNone:   "no aliases, /alias add <name> <expansion> adds one",
+	AliasesNeedId: "aliases need your telegram id, tbot sends it",
+	AliasSaved:    "%s = %s",
+	AliasDeleted:  "alias %s is deleted",
+	AliasNotFound: "no alias %s, /alias list shows aliases",
+	AliasBadName:  "alias %s must be letters, digits or _, up to %d",
+	AliasTaken:    "%s is a command already",
+	AliasCycle:    "aliases loop: %s",
+	AliasTooDeep:  "aliases expand deeper than %d: %s",
+
+	StatusTitle:     "your open tasks:",
+	StatusNone:      "you have no open tasks",
+	HistoryTitle:    "your last dialogs:",
+	HistoryNone:     "no dialogs yet",
+	HistoryNeedId:   "status and history need your telegram id, tbot sends it",
+	HistoryCount:    "the number of dialogs must be positive",
+	HistoryAnswered: "answered",
+	HistoryClosed:   "closed",
+	HistoryFailed:   "failed",
+	HistoryWaiting:  "waits for your reply",
+	Ago:             "%s ago",
+
+	AccessRequested: "access requested, I'll write when the admin approves it",
+	AccessWaiting:   "access is already requested, wait for the admin",
+	AccessClosed:    "access of %s is %s",
+	AccessRequest:   "%s requests access, approve with a role or deny",
+	AccessApproved:  "access approved, your role is %s, /help lists what you can do",
+	AccessDenied:    "access denied",
+	Deny:            "deny",
+	UsersNone:       "no users",
+	UsersTitle:      "users:",
+	UserByName:      "%s %s, by name in the config",
+	UserNotFound:    "user %d not found, /users list shows users",
+	UnknownRole:     "unknown role %s, roles: %s",
+	UserApproved:    "%s is approved as %s",
+	UserNotPending:  "%s is %s, /users role changes it",
+	UserDenied:      "%s is denied",
+	UserNotDeniable: "%s is %s, only access requests are denied",
+	UserRevoked:     "access of %s is revoked",
+	UserNotActive:   "%s is %s, nothing to revoke",
+	RevokeSelf:      "you can't revoke yourself",
+	UserAdded:       "%d is added as %s",
+	UserRoleSet:     "%s is %s now",
+
+	SomethingWrong: "something went wrong, try again later",
+	MessageTooOld:  "the message is too old",
+	RateLimited:    "too many messages, try again in %s",
+	QuotaReached:   "you have %d open %s tasks, the limit is %d, try again when one finishes",
+	BusyReached:    "%d %s tasks are running, the limit is %d, try again when one finishes",
+
+	"status.requested": "requested",
+	"status.active":    "active",
+	"status.denied":    "denied",
+	"status.revoked":   "revoked",
+	// statuses of tasks
+	"status.create":    "waiting for a worker",
+	"status.sended":    "in work",
+	"status.done":      "done",
+	"status.error":     "failed",
+	"status.cancelled": "cancelled",
+	"status.expired":   "expired",
+
+	WorkerText:      "%s",
+	NoteHealthy:     "note is healthy",
+	NotePulled:      "Successfully pulled",
+	NoteAdded:       "text add to %s",
+	NoteWeightAdded: "weight added",
+	NoteBPAdded:     "bp added",
+	NoteEmpty:       "add text is empty",
+}
@@ -0,0 +1,185 @@
+package i18n
+
+var ru = map[Key]string{
+	NotAnswering:     "Я не отвечаю пользователю %s",
+	QuestionAnswered: "на этот вопрос уже ответили",
+	MessageEmpty:     "сообщение пустое",
+	QuoteNotClosed:   "кавычка %c не закрыта",
+	CommandNotFound:  "команда не найдена: %s",
+	NeedSubcommand:   "для %s нужна команда\n%s",
+	UnknownSub:       "неизвестная команда %s для %s",
+	NeedArg:          "для %s нужен %s, использование: %s",
+	NotNumber:        "%s для %s должен быть числом",
+	TooManyArgs:      "слишком много аргументов, использование: %s",
+	NotPermitted:     "нет прав: %s требует %s",
+	ReplyHint:        "ответьте на это сообщение, отмена чтобы прекратить",
+	Cancelled:        "хорошо, отменено",
+	DidYouMean:       "может быть, %s?",
+	IntentYoutube:    "скачать это видео с youtube?",
+	IntentTorrent:    "добавить этот торрент в Download Station?",
+	IntentInbox:      "добавить этот текст во входящие заметок?",
+
+	HelpTitle:     "Мои команды:",
+	HelpMore:      "/help <команда> расскажет подробнее",
+	HelpAliases:   "сокращения: %s",
+	HelpShortcuts: "быстрые команды: %s",
+	NoHelp:        "нет справки для %s",
+
+	TaskCreated:     "задача %s создана",
+	HealthCreated:   "задачи проверки созданы",
+	NotDoneYet:      "ещё не сделано",
+	WorkerOffline:   "нет обработчика для %s, задача выполнится, когда он подключится",
+	TaskExpired:     "ваша задача %s устарела, за %s её никто не взял, попробуйте позже",
+	TaskWaiting:     "ваша задача %s задерживается, она ждёт обработчика",
+	TaskSlow:        "ваша задача %s задерживается, обработчик работает дольше обычного",
+	AlertStuck:      "тревога: задача #%d %s %s в статусе «%s» уже %s",
+	AlertRecovered:  "восстановлено: задача #%d %s %s теперь в статусе «%s», тревога была %s назад",
+	SendTorrent:     "пришлите ссылку на торрент или файл",
+	ConfirmDelete:   "удалить загрузку %s?",
+	DownloadKept:    "хорошо, загрузка остаётся",
+	UnknownCategory: "неизвестная категория: %s",
+	BadUrl:          "не могу разобрать ссылку %s",
+	NotYoutube:      "%s не youtube",
+	StatsDays:       "число дней должно быть положительным",
+	StatsNone:       "нет завершённых задач за %d дн.",
+	StatsTitle:      "задачи за %d дн.",
+	StatsLine:       "%s: готово %d, ошибки %d (%.0f%%), p50 %s, p90 %s, p99 %s",
+	Yes:             "да",
+	No:              "нет",
+
+	SettingsTitle:   "настройки:",
+	SettingNotSet:   "%s не задано, %s",
+	SettingsNeedId:  "для настроек нужен ваш telegram id, его передаёт tbot",
+	SettingCleared:  "%s очищено",
+	UnknownSetting:  "неизвестная настройка %s, настройки: %s",
+	WrongSetting:    "%s: значение %s не подходит, %s",
+	ForeignChat:     "чат %s не ваш, сначала отправьте из него команду",
+	SettingTimezone: "часовой пояс, например Europe/Moscow, по нему датируются заметки",
+	SettingLanguage: "язык ответов: %s",
+	SettingCategory: "категория /ds add, когда она не указана",
+	SettingQuiet:    "часы, например 23-7, когда результаты приходят без звука",
+	SettingNotify:   "id одного из ваших чатов для результатов задач вместо чата команды",
+	SettingIntents:  "ссылки, торренты и текст без команды: confirm спрашивает, auto сразу выполняет, off ничего не делает",
+
+	AliasesTitle:  "алиасы:",
+	AliasesNone:   "алиасов нет, /alias add <имя> <команда> добавит",
+	AliasesNeedId: "для алиасов нужен ваш telegram id, его передаёт tbot",
+	AliasSaved:    "%s = %s",
+	AliasDeleted:  "алиас %s удалён",
+	AliasNotFound: "нет алиаса %s, /alias list покажет алиасы",
+	AliasBadName:  "имя алиаса %s может содержать буквы, цифры и _, до %d символов",
+	AliasTaken:    "%s уже команда",
+	AliasCycle:    "алиасы зациклились: %s",
+	AliasTooDeep:  "алиасы раскрываются глубже %d: %s",
+
+	StatusTitle:     "ваши открытые задачи:",
+	StatusNone:      "у вас нет открытых задач",
+	HistoryTitle:    "ваши последние диалоги:",
+	HistoryNone:     "диалогов пока нет",
+	HistoryNeedId:   "для статуса и истории нужен ваш telegram id, tbot его отправляет",
+	HistoryCount:    "число диалогов должно быть положительным",
+	HistoryAnswered: "отвечен",
+	HistoryClosed:   "закрыт",
+	HistoryFailed:   "ошибка",
+	HistoryWaiting:  "ждёт вашего ответа",
+	Ago:             "%s назад",
+
+	AccessRequested: "доступ запрошен, я напишу, когда админ его одобрит",
+	AccessWaiting:   "доступ уже запрошен, дождитесь админа",
+	AccessClosed:    "доступ %s: %s",
+	AccessRequest:   "%s просит доступ, одобрите с ролью или откажите",
+	AccessApproved:  "доступ одобрен, ваша роль %s, /help покажет, что можно делать",
+	AccessDenied:    "в доступе отказано",
+	Deny:            "отказать",
+	UsersNone:       "пользователей нет",
+	UsersTitle:      "пользователи:",
+	UserByName:      "%s %s, по имени в конфиге",
+	UserNotFound:    "пользователь %d не найден, /users list покажет пользователей",
+	UnknownRole:     "неизвестная роль %s, роли: %s",
+	UserApproved:    "%s одобрен с ролью %s",
+	UserNotPending:  "%s: %s, роль меняет /users role",
+	UserDenied:      "%s отказано",
+	UserNotDeniable: "%s: %s, отказать можно только в запросе доступа",
+	UserRevoked:     "доступ %s отозван",
+	UserNotActive:   "%s: %s, отзывать нечего",
+	RevokeSelf:      "нельзя отозвать доступ у себя",
+	UserAdded:       "%d добавлен с ролью %s",
+	UserRoleSet:     "у %s теперь роль %s",
+
+	SomethingWrong: "что-то пошло не так, попробуйте позже",
+	MessageTooOld:  "сообщение слишком старое",
+	RateLimited:    "слишком много сообщений, попробуйте через %s",
+	QuotaReached:   "у вас %d открытых задач %s, лимит %d, попробуйте, когда одна завершится",
+	BusyReached:    "выполняется %d задач %s, лимит %d, попробуйте, когда одна завершится",
+
+	"status.requested": "запрошен",
+	"status.active":    "активен",
+	"status.denied":    "отказано",
+	"status.revoked":   "отозван",
+	// statuses of tasks
+	"status.create":    "ждёт обработчика",
+	"status.sended":    "в работе",
+	"status.done":      "готово",
+	"status.error":     "ошибка",
+	"status.cancelled": "отменена",
+	"status.expired":   "истекла",
+
+	WorkerText:      "%s",
+	NoteHealthy:     "заметки в порядке",
+	NotePulled:      "репозиторий обновлён",
+	NoteAdded:       "текст добавлен в %s",
+	NoteWeightAdded: "вес добавлен",
+	NoteBPAdded:     "давление добавлено",
+	NoteEmpty:       "текст пустой",
+
+	"cmd.help":                 "список команд",
+	"cmd.ping":                 "проверить, что бот отвечает",
+	"cmd.y2d":                  "скачать видео с youtube в ленту",
+	"cmd.torrent":              "торренты в transmission",
+	"cmd.torrent add":          "добавить ссылку на торрент или приложенный файл, категории: movie/m, shows/s, cartoon/c, cartoon_s/cs, audiobook/a, audiobook_p/ap",
+	"cmd.torrent list":         "торренты в работе",
+	"cmd.torrent del":          "удалить торрент по id",
+	"cmd.note":                 "заметки в git репозитории",
+	"cmd.note 5bx":             "добавить строку 5bx",
+	"cmd.note entry":           "добавить сообщение в дневник",
+	"cmd.note weight":          "добавить вес",
+	"cmd.note bp":              "добавить давление",
+	"cmd.note inbox":           "входящие заметок",
+	"cmd.note inbox add":       "добавить текст во входящие",
+	"cmd.note inbox read":      "прочитать входящие",
+	"cmd.note pull":            "просто обновить репозиторий",
+	"cmd.finance":              "финансовые отчёты",
+	"cmd.finance run":          "построить отчёт",
+	"cmd.finance load":         "загрузить новые данные",
+	"cmd.finance transactions": "показать транзакции",
+	"cmd.ds":                   "Download Station на synology",
+	"cmd.ds add":               "добавить ссылку на торрент или приложенный файл, категории: movie/m, cartoon/c, shows/s, audiobook/a, other/o, shows_cartoons/cs",
+	"cmd.ds list":              "активные загрузки",
+	"cmd.ds del":               "удалить загрузку по id после подтверждения",
+	"cmd.stats":                "длительность и ошибки задач",
+	"cmd.health":               "проверить обработчики",
+	"cmd.settings":             "ваши настройки, их используют обработчики",
+	"cmd.settings list":        "список настроек со значениями",
+	"cmd.settings get":         "показать настройку",
+	"cmd.settings set":         "задать настройку, без значения очищает её",
+	"cmd.status":               "ваши открытые задачи",
+	"cmd.history":              "ваши последние диалоги, число задаёт сколько, команда отбирает её диалоги",
+	"cmd.alias":                "ваши алиасы команд",
+	"cmd.alias list":           "список алиасов",
+	"cmd.alias add":            "добавить алиас, $1..$9 и $* подставляют аргументы",
+	"cmd.alias del":            "удалить алиас",
+	"cmd.users":                "пользователи бота и их роли",
+	"cmd.users list":           "пользователи с ролями и запросы доступа",
+	"cmd.users approve":        "одобрить запрос доступа с ролью, по умолчанию guest",
+	"cmd.users deny":           "отказать в запросе доступа",
+	"cmd.users revoke":         "отозвать доступ у пользователя",
+	"cmd.users role":           "задать роль пользователя, добавляет пользователя по telegram id",
+
+	"ask.y2d.link":             "пришлите ссылку на youtube",
+	"ask.torrent add.category": "какая категория? movie/m, shows/s, cartoon/c, cartoon_s/cs, audiobook/a, audiobook_p/ap",
+	"ask.torrent del.id":       "какой торрент? /torrent list покажет id",
+	"ask.ds add.category":      "какая категория? movie/m, cartoon/c, shows/s, audiobook/a, other/o, shows_cartoons/cs",
+	"ask.ds del.id":            "какая загрузка? /ds list покажет id",
+	"ask.settings get.key":     "какая настройка?",
+	"ask.settings set.key":     "какая настройка?",
+}
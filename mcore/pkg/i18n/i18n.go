@@ -0,0 +1,67 @@
+// Package i18n holds the texts of the bot by language. A text is a fmt
+// format, its args are the parameters of the message, %[2]s takes them
+// out of order when a language needs it.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Lang string
+
+const (
+	En Lang = "en"
+	Ru Lang = "ru"
+)
+
+// Default is the language of users who chose none, texts missing in a
+// catalog come from it too.
+const Default = En
+
+// Key names a text in the catalogs.
+type Key string
+
+var catalogs = map[Lang]map[Key]string{
+	En: en,
+	Ru: ru,
+}
+
+// Langs returns codes of the supported languages.
+func Langs() []string {
+	return []string{string(En), string(Ru)}
+}
+
+// Parse maps a language code like ru or pt-br, as telegram sends it,
+// to a supported language.
+func Parse(code string) (Lang, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(code), "-")
+	lang := Lang(base)
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// T renders the text of key in lang, a key no catalog has is shown as is.
+func T(lang Lang, key Key, args ...any) string {
+	format, ok := catalogs[lang][key]
+	if !ok {
+		format, ok = catalogs[Default][key]
+	}
+	if !ok {
+		format = string(key)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Or returns the text of key in lang, fallback when the catalog of lang
+// lacks it. Texts written next to the code, like summaries of commands,
+// are looked up this way.
+func Or(lang Lang, key Key, fallback string) string {
+	if text, ok := catalogs[lang][key]; ok {
+		return text
+	}
+	return fallback
+}
@@ -0,0 +1,143 @@
+package i18n
+
+// texts of mcore
+const (
+	NotAnswering     Key = "not_answering"
+	QuestionAnswered Key = "question_answered"
+	MessageEmpty     Key = "message_empty"
+	QuoteNotClosed   Key = "quote_not_closed"
+	CommandNotFound  Key = "command_not_found"
+	NeedSubcommand   Key = "need_subcommand"
+	UnknownSub       Key = "unknown_sub"
+	NeedArg          Key = "need_arg"
+	NotNumber        Key = "not_number"
+	TooManyArgs      Key = "too_many_args"
+	NotPermitted     Key = "not_permitted"
+	ReplyHint        Key = "reply_hint"
+	Cancelled        Key = "cancelled"
+	DidYouMean       Key = "did_you_mean"
+	IntentYoutube    Key = "intent.youtube"
+	IntentTorrent    Key = "intent.torrent"
+	IntentInbox      Key = "intent.inbox"
+
+	HelpTitle     Key = "help_title"
+	HelpMore      Key = "help_more"
+	HelpAliases   Key = "help_aliases"
+	HelpShortcuts Key = "help_shortcuts"
+	NoHelp        Key = "no_help"
+
+	TaskCreated     Key = "task_created"
+	HealthCreated   Key = "health_created"
+	NotDoneYet      Key = "not_done_yet"
+	WorkerOffline   Key = "worker_offline"
+	TaskExpired     Key = "task_expired"
+	TaskWaiting     Key = "task_waiting"
+	TaskSlow        Key = "task_slow"
+	AlertStuck      Key = "alert_stuck"
+	AlertRecovered  Key = "alert_recovered"
+	SendTorrent     Key = "send_torrent"
+	ConfirmDelete   Key = "confirm_delete"
+	DownloadKept    Key = "download_kept"
+	UnknownCategory Key = "unknown_category"
+	BadUrl          Key = "bad_url"
+	NotYoutube      Key = "not_youtube"
+	StatsDays       Key = "stats_days"
+	StatsNone       Key = "stats_none"
+	StatsTitle      Key = "stats_title"
+	StatsLine       Key = "stats_line"
+	Yes             Key = "yes"
+	No              Key = "no"
+
+	SettingsTitle   Key = "settings_title"
+	SettingNotSet   Key = "setting_not_set"
+	SettingsNeedId  Key = "settings_need_id"
+	SettingCleared  Key = "setting_cleared"
+	UnknownSetting  Key = "unknown_setting"
+	WrongSetting    Key = "wrong_setting"
+	ForeignChat     Key = "foreign_chat"
+	SettingTimezone Key = "setting.timezone"
+	SettingLanguage Key = "setting.language"
+	SettingCategory Key = "setting.download_category"
+	SettingQuiet    Key = "setting.quiet_hours"
+	SettingNotify   Key = "setting.notify_chat"
+	SettingIntents  Key = "setting.intents"
+
+	AliasesTitle  Key = "aliases_title"
+	AliasesNone   Key = "aliases_none"
+	AliasesNeedId Key = "aliases_need_id"
+	AliasSaved    Key = "alias_saved"
+	AliasDeleted  Key = "alias_deleted"
+	AliasNotFound Key = "alias_not_found"
+	AliasBadName  Key = "alias_bad_name"
+	AliasTaken    Key = "alias_taken"
+	AliasCycle    Key = "alias_cycle"
+	AliasTooDeep  Key = "alias_too_deep"
+
+	StatusTitle     Key = "status_title"
+	StatusNone      Key = "status_none"
+	HistoryTitle    Key = "history_title"
+	HistoryNone     Key = "history_none"
+	HistoryNeedId   Key = "history_need_id"
+	HistoryCount    Key = "history_count"
+	HistoryAnswered Key = "history_answered"
+	HistoryClosed   Key = "history_closed"
+	HistoryFailed   Key = "history_failed"
+	HistoryWaiting  Key = "history_waiting"
+	Ago             Key = "ago"
+
+	AccessRequested Key = "access_requested"
+	AccessWaiting   Key = "access_waiting"
+	AccessClosed    Key = "access_closed"
+	AccessRequest   Key = "access_request"
+	AccessApproved  Key = "access_approved"
+	AccessDenied    Key = "access_denied"
+	Deny            Key = "deny"
+	UsersNone       Key = "users_none"
+	UsersTitle      Key = "users_title"
+	UserByName      Key = "user_by_name"
+	UserNotFound    Key = "user_not_found"
+	UnknownRole     Key = "unknown_role"
+	UserApproved    Key = "user_approved"
+	UserNotPending  Key = "user_not_pending"
+	UserDenied      Key = "user_denied"
+	UserNotDeniable Key = "user_not_deniable"
+	UserRevoked     Key = "user_revoked"
+	UserNotActive   Key = "user_not_active"
+	RevokeSelf      Key = "revoke_self"
+	UserAdded       Key = "user_added"
+	UserRoleSet     Key = "user_role_set"
+
+	SomethingWrong Key = "something_wrong"
+	MessageTooOld  Key = "message_too_old"
+	RateLimited    Key = "rate_limited"
+	QuotaReached   Key = "quota_reached"
+	BusyReached    Key = "busy_reached"
+)
+
+// texts of workers, they send the key and its args in the report
+const (
+	// WorkerText shows the text of a worker as is
+	WorkerText      Key = "worker.text"
+	NoteHealthy     Key = "note.healthy"
+	NotePulled      Key = "note.pulled"
+	NoteAdded       Key = "note.added"
+	NoteWeightAdded Key = "note.weight_added"
+	NoteBPAdded     Key = "note.bp_added"
+	NoteEmpty       Key = "note.empty"
+)
+
+// Status is the key of a status name, e.g. of a user status.
+func Status(name string) Key {
+	return Key("status." + name)
+}
+
+// Summary is the key of the summary of the command at path,
+// e.g. "note inbox add".
+func Summary(path string) Key {
+	return Key("cmd." + path)
+}
+
+// Prompt is the key of the question for arg of the command at path.
+func Prompt(path, arg string) Key {
+	return Key("ask." + path + "." + arg)
+}
@@ -10,29 +10,40 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"time"
 )
 
 type Client struct {
 	addr    string
 	secret  string
+	worker  string
 	timeout time.Duration
 }
 
 const (
 	addMsgUrl     = "/add-msg/"
+	botMsgUrl     = "/bot-msg/"
+	callbackUrl   = "/callback/"
 	getTaskUrl    = "/get-task/"
 	reportTaskUrl = "/report-task/"
 )
 
 func NewClient(addr, secret string) *Client {
+	worker, _ := os.Hostname()
 	return &Client{
 		addr:    addr,
 		secret:  secret,
+		worker:  worker,
 		timeout: 10 * time.Second,
 	}
 }
 
+// SetWorker names the worker in the task log of mcore, hostname by default.
+func (c *Client) SetWorker(worker string) {
+	c.worker = worker
+}
+
 func (c *Client) AddMsg(msgRes schema.Message) (schema.AddMsgReq, error) {
 	var mr schema.AddMsgReq
 	body, err := json.Marshal(msgRes)
@@ -52,8 +63,52 @@ func (c *Client) AddMsg(msgRes schema.Message) (schema.AddMsgReq, error) {
 	return mr, nil
 }
 
+// AddCallback sends a pressed inline button, the answer is like AddMsg's.
+func (c *Client) AddCallback(cb schema.CallbackReq) (schema.AddMsgReq, error) {
+	var mr schema.AddMsgReq
+	body, err := json.Marshal(cb)
+	if err != nil {
+		return mr, fmt.Errorf("callback marshal err %w", err)
+	}
+
+	reqBody, err := c.doPost(callbackUrl, body)
+	if err != nil {
+		return mr, fmt.Errorf("callback doPost: %w", err)
+	}
+	err = json.Unmarshal(reqBody, &mr)
+	if err != nil {
+		return mr, fmt.Errorf("callback Unmarshal req: %w", err)
+	}
+
+	return mr, nil
+}
+
+// AddBotMsg links a message the bot sent to its dialog,
+// a reply of the user to it continues the dialog.
+func (c *Client) AddBotMsg(botMsg schema.BotMsgReq) (schema.Req, error) {
+	var r schema.Req
+	body, err := json.Marshal(botMsg)
+	if err != nil {
+		return r, fmt.Errorf("botMsg marshal err %w", err)
+	}
+
+	reqBody, err := c.doPost(botMsgUrl, body)
+	if err != nil {
+		return r, fmt.Errorf("botMsg doPost: %w", err)
+	}
+	err = json.Unmarshal(reqBody, &r)
+	if err != nil {
+		return r, fmt.Errorf("botMsg Unmarshal req: %w", err)
+	}
+
+	return r, nil
+}
+
 func (c *Client) GetTask(taskReq schema.GetTaskReq) (schema.GetTaskRes, error) {
 	var tr schema.GetTaskRes
+	if taskReq.Worker == "" {
+		taskReq.Worker = c.worker
+	}
 	body, err := json.Marshal(taskReq)
 	if err != nil {
 		return tr, fmt.Errorf("getTask marshal err %w", err)
@@ -63,6 +118,10 @@ func (c *Client) GetTask(taskReq schema.GetTaskReq) (schema.GetTaskRes, error) {
 	if err != nil {
 		return tr, fmt.Errorf("getTask doPost: %w", err)
 	}
+	if reqBody == nil {
+		tr.Status = "no tasks"
+		return tr, nil
+	}
 	err = json.Unmarshal(reqBody, &tr)
 	if err != nil {
 		return tr, fmt.Errorf("getTask Unmarshal req: %w", err)
@@ -73,6 +132,9 @@ func (c *Client) GetTask(taskReq schema.GetTaskReq) (schema.GetTaskRes, error) {
 
 func (c *Client) ReportTask(taskReq schema.ReportTaskReq) (schema.Req, error) {
 	var tr schema.Req
+	if taskReq.Worker == "" {
+		taskReq.Worker = c.worker
+	}
 	body, err := json.Marshal(taskReq)
 	if err != nil {
 		return tr, fmt.Errorf("getTask marshal err %w", err)
@@ -119,11 +181,8 @@ func (c *Client) ListeningTasks(ctx context.Context, taskType schema.TaskType, t
 					logger.Debug("dotask run")
 					result := taskWorker.DoTask(task.Data)
 					logger.Debug("dotask result:" + result.TextMsg)
-					_, err = c.ReportTask(schema.ReportTaskReq{
-						TaskId:  task.Data.Id,
-						Status:  result.Status,
-						TextMsg: result.TextMsg,
-					})
+					result.TaskId = task.Data.Id
+					_, err = c.ReportTask(result)
 					if err != nil {
 						log.Printf("can't report: %s", err.Error())
 					}
@@ -149,13 +208,16 @@ func (c *Client) doPost(url string, body []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("doPost http request %w", err)
 	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("doPost some error status %d", resp.StatusCode)
-	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("doPost read body %w", err)
 	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newError(resp.StatusCode, respBody)
+	}
 	return respBody, nil
 }
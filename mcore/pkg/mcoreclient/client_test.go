@@ -0,0 +1,65 @@
+package mcoreclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+type noteWorker struct{}
+
+func (noteWorker) DoTask(task schema.Task) schema.ReportTaskReq {
+	return schema.ReportTaskReq{
+		Status:  schema.TaskStatusDone,
+		TextMsg: "text add to inbox",
+		MsgKey:  "note.added",
+		MsgArgs: []string{"inbox"},
+	}
+}
+
+func TestListeningTasksReportsKey(t *testing.T) {
+	reports := make(chan schema.ReportTaskReq, 1)
+	var served atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case getTaskUrl:
+			if served.Swap(true) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(schema.GetTaskRes{Status: "OK", Data: schema.Task{Id: 7, Type: schema.TaskTypeNote}})
+		case reportTaskUrl:
+			var req schema.ReportTaskReq
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				t.Errorf("decode report: %s", err)
+			}
+			reports <- req
+			_ = json.NewEncoder(w).Encode(schema.Req{Status: "OK"})
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewClient(srv.URL, "secret")
+	c.ListeningTasks(ctx, schema.TaskTypeNote, noteWorker{}, time.Millisecond)
+
+	select {
+	case got := <-reports:
+		if got.TaskId != 7 || got.Status != schema.TaskStatusDone {
+			t.Errorf("report of task %d with %s, want 7 done", got.TaskId, got.Status)
+		}
+		if got.MsgKey != "note.added" || len(got.MsgArgs) != 1 || got.MsgArgs[0] != "inbox" {
+			t.Errorf("report has key %q args %v, want note.added [inbox]", got.MsgKey, got.MsgArgs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no report")
+	}
+}
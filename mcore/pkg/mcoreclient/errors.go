@@ -0,0 +1,42 @@
+package mcoreclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+// Error is returned when mcore answers with a non 2xx status.
+// It unwraps to schema.Error, so schema.CodeOf and schema.IsUserError
+// work on it.
+type Error struct {
+	StatusCode int
+	Code       schema.ErrorCode
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mcore status %d code %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return schema.NewError(e.Code, e.Message)
+}
+
+func newError(statusCode int, body []byte) *Error {
+	var er schema.ErrorRes
+	err := json.Unmarshal(body, &er)
+	if err != nil || er.Code == "" {
+		return &Error{
+			StatusCode: statusCode,
+			Code:       schema.ErrCodeInternal,
+			Message:    fmt.Sprintf("some error status %d", statusCode),
+		}
+	}
+	return &Error{
+		StatusCode: statusCode,
+		Code:       er.Code,
+		Message:    er.Error,
+	}
+}
@@ -0,0 +1,245 @@
+// Package mcoretest starts a fully wired mcore in memory for end-to-end
+// tests of workers:
+//
+//	srv := mcoretest.NewServer(t)
+//	srv.SendMessage(mcoretest.User, "/note inbox add milk")
+//	task := srv.WaitTask(schema.TaskTypeNote, time.Second)
+//	srv.ReportTask(task.Id, schema.TaskStatusDone, "text add to inbox")
+//	reply := srv.WaitReply(time.Second)
+package mcoretest
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/internal/access"
+	"github.com/ishua/a3bot6/mcore/internal/dialogmng"
+	"github.com/ishua/a3bot6/mcore/internal/functions"
+	"github.com/ishua/a3bot6/mcore/internal/rest"
+	"github.com/ishua/a3bot6/mcore/internal/routing"
+	"github.com/ishua/a3bot6/mcore/internal/storage/memstore"
+	"github.com/ishua/a3bot6/mcore/internal/taskmng"
+	"github.com/ishua/a3bot6/mcore/pkg/mcoreclient"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+const (
+	// Secret is accepted by the test server.
+	Secret = "test"
+	// User is allowed to talk to the test server.
+	User = "testUser"
+	// UserId is the telegram id of User, users given to NewServer get ids
+	// from it on, other users the ids after them.
+	UserId int64 = 100
+	// ChatId is the chat of messages sent by SendMessage.
+	ChatId int64 = 1
+
+	pollInterval = 10 * time.Millisecond
+)
+
+type Server struct {
+	// URL of the server, pass it to workers as mcore address.
+	URL string
+	// Client is a mcore client authorized with Secret.
+	Client *mcoreclient.Client
+
+	t      testing.TB
+	srv    *httptest.Server
+	mu     sync.Mutex
+	lastId int
+	// telegram ids by user name
+	ids map[string]int64
+	// message id given to the last quick reply of the bot
+	lastBotId int
+}
+
+// NewServer starts mcore with users as admins seeded by their ids, User
+// when none given.
+// The server is closed when the test ends.
+func NewServer(t testing.TB, users ...string) *Server {
+	t.Helper()
+	if len(users) == 0 {
+		users = []string{User}
+	}
+	ids := map[string]int64{}
+	admins := make([]string, 0, len(users))
+	for i, user := range users {
+		ids[user] = UserId + int64(i)
+		admins = append(admins, strconv.FormatInt(ids[user], 10))
+	}
+
+	db := memstore.NewMemStore()
+	policy, err := access.New(access.Config{}, admins, db)
+	if err != nil {
+		t.Fatalf("mcoretest access: %v", err)
+	}
+	err = policy.Seed()
+	if err != nil {
+		t.Fatalf("mcoretest seed users: %v", err)
+	}
+	taskMng, err := taskmng.NewTaskMng(db, taskmng.Config{}, policy)
+	if err != nil {
+		t.Fatalf("mcoretest task manager: %v", err)
+	}
+	dialogMng := dialogmng.NewDialogMng(db)
+	funcMng := functions.NewMng(db)
+	router := routing.NewRouter(routing.Config{}, policy, dialogMng, taskMng)
+	api := rest.NewApi("", taskMng, router, funcMng, db, false, []string{Secret}, "", "test", t.TempDir())
+
+	srv := httptest.NewServer(api.Handler())
+	t.Cleanup(srv.Close)
+
+	return &Server{
+		URL:    srv.URL,
+		Client: mcoreclient.NewClient(srv.URL, Secret),
+		t:      t,
+		srv:    srv,
+		ids:    ids,
+	}
+}
+
+// userId is the telegram id of user, a user unknown to the server gets
+// the next free one.
+func (s *Server) userId(user string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.ids[user]
+	if !ok {
+		id = UserId + int64(len(s.ids))
+		s.ids[user] = id
+	}
+	return id
+}
+
+// SendMessage sends text to mcore as user from ChatId, like tbot does,
+// and returns the immediate reply. User mistakes come back as errors
+// with a schema.ErrorCode.
+func (s *Server) SendMessage(user, text string) (schema.TaskMsg, error) {
+	return s.Send(schema.Message{
+		UserId:   s.userId(user),
+		UserName: user,
+		ChatId:   ChatId,
+		Text:     text,
+	})
+}
+
+// Send sends m, a zero MessageId is replaced by the next free one.
+// The quick reply gets the next id too and is linked to its dialog,
+// as tbot does after sending it.
+func (s *Server) Send(m schema.Message) (schema.TaskMsg, error) {
+	s.t.Helper()
+	s.mu.Lock()
+	s.lastId++
+	if m.MessageId == 0 {
+		m.MessageId = s.lastId
+	}
+	s.mu.Unlock()
+
+	res, err := s.Client.AddMsg(m)
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	s.sent(res)
+	return res.Data, nil
+}
+
+// Press presses the button with data under the last quick reply of the bot.
+func (s *Server) Press(user, data string) (schema.TaskMsg, error) {
+	s.t.Helper()
+	userId := s.userId(user)
+	s.mu.Lock()
+	botId := s.lastBotId
+	s.mu.Unlock()
+
+	res, err := s.Client.AddCallback(schema.CallbackReq{
+		ChatId:    ChatId,
+		UserId:    userId,
+		UserName:  user,
+		MessageId: botId,
+		Data:      data,
+	})
+	if err != nil {
+		return schema.TaskMsg{}, err
+	}
+	s.sent(res)
+	return res.Data, nil
+}
+
+// sent gives the quick reply the next message id and links it to its dialog.
+func (s *Server) sent(res schema.AddMsgReq) {
+	s.t.Helper()
+	if res.DialogId == 0 || res.Data.Text == "" {
+		return
+	}
+
+	s.mu.Lock()
+	s.lastId++
+	botId := s.lastId
+	s.lastBotId = botId
+	s.mu.Unlock()
+	_, err := s.Client.AddBotMsg(schema.BotMsgReq{DialogId: res.DialogId, ChatId: res.Data.ChatId, MessageId: botId})
+	if err != nil {
+		s.t.Fatalf("mcoretest bot msg: %v", err)
+	}
+}
+
+// Answer sends text as a reply to the last quick reply of the bot,
+// which continues a dialog waiting for the answer.
+func (s *Server) Answer(user, text string) (schema.TaskMsg, error) {
+	userId := s.userId(user)
+	s.mu.Lock()
+	replyTo := s.lastBotId
+	s.mu.Unlock()
+	return s.Send(schema.Message{
+		UserId:           userId,
+		UserName:         user,
+		ChatId:           ChatId,
+		Text:             text,
+		ReplyToMessageID: replyTo,
+	})
+}
+
+// WaitTask claims the next task of taskType as a worker would,
+// the test fails when none comes within timeout.
+func (s *Server) WaitTask(taskType schema.TaskType, timeout time.Duration) schema.Task {
+	s.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		res, err := s.Client.GetTask(schema.GetTaskReq{TaskType: taskType})
+		if err != nil {
+			s.t.Fatalf("mcoretest wait task %d: %v", taskType, err)
+		}
+		if res.Data.Id != 0 {
+			return res.Data
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("mcoretest no task of type %d in %s", taskType, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// ReportTask reports the result of a task, the test fails on error.
+func (s *Server) ReportTask(taskId int64, status schema.TaskStatus, textMsg string) {
+	s.t.Helper()
+	_, err := s.Client.ReportTask(schema.ReportTaskReq{
+		TaskId:  taskId,
+		Status:  status,
+		TextMsg: textMsg,
+	})
+	if err != nil {
+		s.t.Fatalf("mcoretest report task %d: %v", taskId, err)
+	}
+}
+
+// WaitReply waits for the next message for the user, acts as tbot
+// and reports it as sent.
+func (s *Server) WaitReply(timeout time.Duration) schema.TaskMsg {
+	s.t.Helper()
+	task := s.WaitTask(schema.TaskTypeMsg, timeout)
+	s.ReportTask(task.Id, schema.TaskStatusDone, "message sent")
+	return task.TaskData.Msg
+}
@@ -0,0 +1,95 @@
+package mcoretest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ishua/a3bot6/mcore/pkg/mcoretest"
+	"github.com/ishua/a3bot6/mcore/pkg/schema"
+)
+
+func TestNoteRoundTrip(t *testing.T) {
+	srv := mcoretest.NewServer(t)
+	_, err := srv.SendMessage(mcoretest.User, "/note inbox add milk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := srv.WaitTask(schema.TaskTypeNote, time.Second)
+	if task.TaskData.Tn.AddText != "milk" {
+		t.Errorf("note text %q, want milk", task.TaskData.Tn.AddText)
+	}
+	srv.ReportTask(task.Id, schema.TaskStatusDone, "text add to inbox")
+	reply := srv.WaitReply(time.Second)
+	if reply.Text != "text add to inbox" || reply.ChatId != mcoretest.ChatId {
+		t.Errorf("reply %q to chat %d", reply.Text, reply.ChatId)
+	}
+}
+
+// aliases, settings and /status need the telegram id of the user
+func TestUserCommands(t *testing.T) {
+	srv := mcoretest.NewServer(t)
+	for _, text := range []string{
+		"/alias add milk note inbox add milk",
+		"/settings set timezone Europe/Moscow",
+		"/milk",
+	} {
+		_, err := srv.SendMessage(mcoretest.User, text)
+		if err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+	}
+	task := srv.WaitTask(schema.TaskTypeNote, time.Second)
+	if task.TaskData.Settings[schema.SettingTimezone] != "Europe/Moscow" {
+		t.Errorf("task settings %v, want the timezone", task.TaskData.Settings)
+	}
+
+	reply, err := srv.SendMessage(mcoretest.User, "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply.Text, "#1 note") {
+		t.Errorf("status %q does not list the note task", reply.Text)
+	}
+}
+
+func TestAccessRequest(t *testing.T) {
+	srv := mcoretest.NewServer(t)
+	reply, err := srv.SendMessage("stranger", "/note inbox add milk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply.Text, "access requested") {
+		t.Errorf("stranger got %q, want an access request", reply.Text)
+	}
+	// stranger got the id after the users of the server
+	_, err = srv.SendMessage(mcoretest.User, fmt.Sprintf("/users approve %d family", mcoretest.UserId+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply = srv.WaitReply(time.Second)
+	if reply.ChatId != mcoretest.ChatId || !strings.Contains(reply.Text, "access approved") {
+		t.Errorf("stranger got %q in chat %d, want the approval", reply.Text, reply.ChatId)
+	}
+}
+
+func TestAskAndAnswer(t *testing.T) {
+	srv := mcoretest.NewServer(t)
+	question, err := srv.SendMessage(mcoretest.User, "/y2d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(question.Text, "youtube link") {
+		t.Fatalf("question %q, want the link", question.Text)
+	}
+	link := "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	_, err = srv.Answer(mcoretest.User, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := srv.WaitTask(schema.TaskTypeYtdl, time.Second)
+	if task.TaskData.Ytdl.Link != link {
+		t.Errorf("link %q, want %q", task.TaskData.Ytdl.Link, link)
+	}
+}
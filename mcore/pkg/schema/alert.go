@@ -0,0 +1,11 @@
+package schema
+
+import "time"
+
+// TaskAlert marks a task the watchdog has alerted about while it was
+// stuck in Status, it is removed when the task moves on.
+type TaskAlert struct {
+	TaskId    int64      `json:"taskId"`
+	Status    TaskStatus `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
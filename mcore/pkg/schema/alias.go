@@ -0,0 +1,11 @@
+package schema
+
+// Aliases of a user by name, an alias stands for the beginning of a command.
+type Aliases map[string]string
+
+// UserAlias is one row of the aliases, for exports.
+type UserAlias struct {
+	UserId    int64  `json:"userId"`
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+}
@@ -3,6 +3,7 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type MessageType int
@@ -19,7 +20,8 @@ const (
 	DialogStatusUndefined = iota
 	DialogStatusError
 	DialogStatusBegin
-	DialogStatusClose = 100
+	DialogStatusWaitReply // bot asked a question, a reply to it continues the dialog
+	DialogStatusClose     = 100
 )
 
 type Dialog struct {
@@ -27,17 +29,73 @@ type Dialog struct {
 	Key          string       `json:"key"`
 	DialogStatus DialogStatus `json:"dialogStatus"`
 	Messages     []Message    `json:"messages"`
+	State        DialogState  `json:"state"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
 }
 
+// DialogState keeps a command that waits for an answer of the user.
+type DialogState struct {
+	// Path names the command, e.g. ds add
+	Path    []string          `json:"path,omitempty"`
+	Args    map[string]string `json:"args,omitempty"`
+	FileUrl string            `json:"fileUrl,omitempty"`
+	// Ask is the arg the answer goes to
+	Ask      string     `json:"ask,omitempty"`
+	Question string     `json:"question,omitempty"`
+	Buttons  [][]Button `json:"buttons,omitempty"`
+}
+
+// ChatTypePrivate is the chat type of a one to one chat with the bot,
+// others are group, supergroup and channel.
+const ChatTypePrivate = "private"
+
 type Message struct {
+	// UserId and UserName are of the sender, in a group chat too
+	UserId           int64       `json:"userId"`
 	UserName         string      `json:"userName"`
 	MessageId        int         `json:"messageId"`
 	ReplyToMessageID int         `json:"replyToMessageID"`
 	ChatId           int64       `json:"chatId"`
+	ChatType         string      `json:"chatType"`
 	Text             string      `json:"text"`
 	Caption          string      `json:"caption"`
 	FileUrl          string      `json:"fileUrl"`
 	Type             MessageType `json:"type"`
+	// LanguageCode of the telegram of the sender, e.g. ru
+	LanguageCode string `json:"languageCode,omitempty"`
+	// CreatedAt is when mcore got or sent the message
+	CreatedAt time.Time `json:"createdAt"`
+	// TaskId is the task a message of the bot reports on, 0 for direct replies
+	TaskId int64 `json:"taskId,omitempty"`
+}
+
+// BotMsgReq tells mcore which telegram message the bot sent for a dialog,
+// replies to that message continue the dialog.
+type BotMsgReq struct {
+	DialogId  int64 `json:"dialogId"`
+	ChatId    int64 `json:"chatId"`
+	MessageId int   `json:"messageId"`
+}
+
+// CallbackReq is a pressed inline button, MessageId is the bot message
+// the button belongs to.
+type CallbackReq struct {
+	ChatId       int64  `json:"chatId"`
+	UserId       int64  `json:"userId"`
+	UserName     string `json:"userName"`
+	MessageId    int    `json:"messageId"`
+	Data         string `json:"data"`
+	LanguageCode string `json:"languageCode,omitempty"`
+}
+
+// UserId is the telegram id of the user who began the dialog, 0 when
+// their message came without one.
+func (d *Dialog) UserId() int64 {
+	if len(d.Messages) == 0 {
+		return 0
+	}
+	return d.Messages[0].UserId
 }
 
 func (d *Dialog) GetMessagesAsByte() ([]byte, error) {
@@ -52,6 +110,24 @@ func (d *Dialog) SetMessagesFromByte(b []byte) error {
 	return nil
 }
 
+func (d *Dialog) GetStateAsByte() ([]byte, error) {
+	return json.Marshal(d.State)
+}
+
+// SetStateFromByte accepts an empty state of dialogs saved before states.
+func (d *Dialog) SetStateFromByte(b []byte) error {
+	d.State = DialogState{}
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, &d.State)
+}
+
+// GenerateKey names the user in the chat, by name for messages
+// without a user id.
 func GenerateKey(m Message) string {
+	if m.UserId != 0 {
+		return fmt.Sprintf("%d-%d", m.ChatId, m.UserId)
+	}
 	return fmt.Sprintf("%d-%s", m.ChatId, m.UserName)
 }
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
+)
+
+type ErrorCode string
+
+const (
+	ErrCodeInternal         ErrorCode = "internal"
+	ErrCodeBadRequest       ErrorCode = "bad_request"
+	ErrCodeUnauthorized     ErrorCode = "unauthorized"
+	ErrCodeUnknownCommand   ErrorCode = "unknown_command"
+	ErrCodeInvalidArgument  ErrorCode = "invalid_argument"
+	ErrCodeUnauthorizedUser ErrorCode = "unauthorized_user"
+	ErrCodeNotPermitted     ErrorCode = "not_permitted"
+	ErrCodeNotFound         ErrorCode = "not_found"
+	ErrCodeStorageFailure   ErrorCode = "storage_failure"
+	// ErrCodeRateLimited answers a user over a rate limit or a quota
+	ErrCodeRateLimited ErrorCode = "rate_limited"
+)
+
+// Error is an error with a machine-readable code. Codes are part of the
+// rest api, clients use them to tell user mistakes from server faults.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+	// Key and Args render the message in the language of the user,
+	// Message holds it in the default one
+	Key  i18n.Key
+	Args []any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewError(code ErrorCode, msg string) *Error {
+	return &Error{Code: code, Message: msg}
+}
+
+// Errorf formats like fmt.Errorf, a %w verb keeps the wrapped error.
+func Errorf(code ErrorCode, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+// Localized is an error with a text of the catalogs.
+func Localized(code ErrorCode, key i18n.Key, args ...any) *Error {
+	return &Error{Code: code, Message: i18n.T(i18n.Default, key, args...), Key: key, Args: args}
+}
+
+// Translate returns err with the message in lang when the first Error in
+// its chain has a key, other errors come back as they are.
+func Translate(err error, lang i18n.Lang) error {
+	var e *Error
+	if !errors.As(err, &e) || e.Key == "" {
+		return err
+	}
+	t := *e
+	t.Message = i18n.T(lang, e.Key, e.Args...)
+	return &t
+}
+
+// CodeOf returns the code of the first Error in err's chain,
+// errors without a code are internal.
+func CodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ErrCodeInternal
+}
+
+// IsUserError reports whether err was caused by the user input
+// and its text can be shown to the user as is.
+func IsUserError(err error) bool {
+	switch CodeOf(err) {
+	case ErrCodeUnknownCommand, ErrCodeInvalidArgument, ErrCodeUnauthorizedUser, ErrCodeNotPermitted, ErrCodeRateLimited:
+		return true
+	}
+	return false
+}
@@ -6,6 +6,7 @@ type Req struct {
 }
 type GetTaskReq struct {
 	TaskType TaskType `json:"taskType"`
+	Worker   string   `json:"worker"`
 }
 
 type GetTaskRes struct {
@@ -18,10 +19,36 @@ type ReportTaskReq struct {
 	TaskId  int64      `json:"taskId"`
 	Status  TaskStatus `json:"status"`
 	TextMsg string     `json:"textMsg"`
+	Worker  string     `json:"worker"`
+	// MessageId of the telegram message sent for a msg task
+	MessageId int `json:"messageId"`
+	// MsgKey names the text in the catalogs of mcore, the user gets it in
+	// their language with MsgArgs, TextMsg is the text for logs and the
+	// fallback
+	MsgKey  string   `json:"msgKey,omitempty"`
+	MsgArgs []string `json:"msgArgs,omitempty"`
+}
+
+type CancelTaskReq struct {
+	TaskId int64  `json:"taskId"`
+	Reason string `json:"reason"`
 }
 
 type AddMsgReq struct {
-	Data   TaskMsg `json:"taskMsg"`
-	Status string  `json:"status"`
-	Error  string  `json:"error"`
+	Data     TaskMsg `json:"taskMsg"`
+	DialogId int64   `json:"dialogId"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error"`
+}
+
+type ErrorRes struct {
+	Error  string    `json:"error"`
+	Code   ErrorCode `json:"code"`
+	Status string    `json:"status"`
+}
+
+type BackupRes struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
 }
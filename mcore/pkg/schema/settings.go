@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keys of user settings
+const (
+	SettingTimezone         = "timezone"
+	SettingLanguage         = "language"
+	SettingDownloadCategory = "download_category"
+	SettingQuietHours       = "quiet_hours"
+	SettingNotifyChat       = "notify_chat"
+	SettingIntents          = "intents"
+)
+
+// what happens to links, torrents and text sent without a command
+const (
+	IntentsConfirm = "confirm" // ask before running the guessed command
+	IntentsAuto    = "auto"
+	IntentsOff     = "off"
+)
+
+// Settings of a user by key, a task carries the ones its worker uses.
+type Settings map[string]string
+
+// UserSetting is one row of the settings, for exports.
+type UserSetting struct {
+	UserId int64  `json:"userId"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+// Location is the time zone of the user, time.Local when it is not set.
+func (s Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s[SettingTimezone])
+	if err != nil || s[SettingTimezone] == "" {
+		return time.Local
+	}
+	return loc
+}
+
+// Now is the current time of the user.
+func (s Settings) Now() time.Time {
+	return time.Now().In(s.Location())
+}
+
+// Quiet reports whether t falls into the quiet hours of the user.
+func (s Settings) Quiet(t time.Time) bool {
+	from, to, err := ParseQuietHours(s[SettingQuietHours])
+	if err != nil {
+		return false
+	}
+	h := t.In(s.Location()).Hour()
+	if from <= to {
+		return h >= from && h < to
+	}
+	return h >= from || h < to
+}
+
+// ParseQuietHours parses hours like 23-7, the end hour is not quiet.
+func ParseQuietHours(v string) (int, int, error) {
+	fromText, toText, ok := strings.Cut(v, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("quiet hours %q are not like 23-7", v)
+	}
+	from, err := strconv.Atoi(strings.TrimSpace(fromText))
+	if err != nil || from < 0 || from > 23 {
+		return 0, 0, fmt.Errorf("quiet hours %q start with a wrong hour", v)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(toText))
+	if err != nil || to < 0 || to > 23 {
+		return 0, 0, fmt.Errorf("quiet hours %q end with a wrong hour", v)
+	}
+	return from, to, nil
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"encoding/json"
+	"time"
 )
 
 type TaskType int
@@ -17,6 +18,33 @@ const (
 	TaskTypeSyno
 )
 
+var taskTypeNames = map[TaskType]string{
+	TaskTypeMsg:     "msg",
+	TaskTypeYtdl:    "ytdl",
+	TaskTypeRest:    "rest",
+	TaskTypeNote:    "note",
+	TaskTypeTorrent: "torrent",
+	TaskTypeFinance: "finance",
+	TaskTypeSyno:    "syno",
+}
+
+func (t TaskType) String() string {
+	if name, ok := taskTypeNames[t]; ok {
+		return name
+	}
+	return "undefined"
+}
+
+// ParseTaskType is the reverse of String, for task types in configs.
+func ParseTaskType(name string) (TaskType, bool) {
+	for t, n := range taskTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return TaskTypeUndefined, false
+}
+
 type TaskStatus int
 
 const (
@@ -25,14 +53,34 @@ const (
 	TaskStatusError     //worker can't complete the task
 	TaskStatusSended    //worker recived the task for work
 	TaskStatusDone      // worker completed the task
+	TaskStatusCancelled //admin stopped the task, nobody does it
+	TaskStatusExpired   //no worker claimed the task in time
 )
 
+var taskStatusNames = map[TaskStatus]string{
+	TaskStatusCreate:    "create",
+	TaskStatusError:     "error",
+	TaskStatusSended:    "sended",
+	TaskStatusDone:      "done",
+	TaskStatusCancelled: "cancelled",
+	TaskStatusExpired:   "expired",
+}
+
+func (s TaskStatus) String() string {
+	if name, ok := taskStatusNames[s]; ok {
+		return name
+	}
+	return "undefined"
+}
+
 type Task struct {
-	Id       int64      `json:"id"`
-	DialogId int64      `json:"dialogId"`
-	Status   TaskStatus `json:"status"`
-	Type     TaskType   `json:"type"`
-	TaskData TaskData   `json:"taskData"`
+	Id        int64      `json:"id"`
+	DialogId  int64      `json:"dialogId"`
+	Status    TaskStatus `json:"status"`
+	Type      TaskType   `json:"type"`
+	TaskData  TaskData   `json:"taskData"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
 }
 
 type TaskData struct {
@@ -43,12 +91,22 @@ type TaskData struct {
 	Tn     TaskNote    `json:"tn"`
 	Fin    FinanceTask `json:"fin"`
 	Syno   TaskSyno    `json:"syno"`
+	// Settings of the user the worker needs, e.g. the timezone for notes
+	Settings Settings `json:"settings,omitempty"`
 }
 
 type TaskMsg struct {
 	Text           string `json:"text"`
 	ChatId         int64  `json:"chatId"`
 	ReplyMessageId int    `json:"replyMessageId"`
+	// Buttons are rows of an inline keyboard under the text
+	Buttons [][]Button `json:"buttons,omitempty"`
+}
+
+// Button sends Data back to mcore with a callback when it is pressed.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
 }
 
 type TaskYtdl struct {
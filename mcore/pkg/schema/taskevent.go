@@ -0,0 +1,79 @@
+package schema
+
+import "time"
+
+type TaskEventKind string
+
+const (
+	TaskEventCreated   TaskEventKind = "created"
+	TaskEventClaimed   TaskEventKind = "claimed"
+	TaskEventProgress  TaskEventKind = "progress"
+	TaskEventRetried   TaskEventKind = "retried"
+	TaskEventDone      TaskEventKind = "done"
+	TaskEventError     TaskEventKind = "error"
+	TaskEventCancelled TaskEventKind = "cancelled"
+	TaskEventExpired   TaskEventKind = "expired"
+)
+
+// TaskEvent is one transition of a task. Events are only appended,
+// type and command are copied from the task to group stats without joins.
+type TaskEvent struct {
+	Id        int64         `json:"id"`
+	TaskId    int64         `json:"taskId"`
+	TaskType  TaskType      `json:"taskType"`
+	Command   string        `json:"command"`
+	Kind      TaskEventKind `json:"kind"`
+	Worker    string        `json:"worker"`
+	Message   string        `json:"message"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// Command names what the task does inside its type, e.g. addInbox for notes.
+func (t Task) Command() string {
+	switch t.Type {
+	case TaskTypeMsg:
+		return "send"
+	case TaskTypeYtdl:
+		if t.TaskData.Health != "" {
+			return "health"
+		}
+		return "download"
+	case TaskTypeNote:
+		if t.TaskData.Health != "" {
+			return "health"
+		}
+		return string(t.TaskData.Tn.Command)
+	case TaskTypeTorrent:
+		return t.TaskData.Tr.Command
+	case TaskTypeFinance:
+		return t.TaskData.Fin.Command
+	case TaskTypeSyno:
+		return string(t.TaskData.Syno.Command)
+	}
+	return ""
+}
+
+// TaskStat sums up finished tasks of one type, or of one command
+// when Command is set. Durations are from creation to done or error.
+type TaskStat struct {
+	TaskType    TaskType      `json:"taskType"`
+	Command     string        `json:"command,omitempty"`
+	Finished    int           `json:"finished"`
+	Failed      int           `json:"failed"`
+	FailureRate float64       `json:"failureRate"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+}
+
+type TaskStats struct {
+	Since     time.Time  `json:"since"`
+	ByType    []TaskStat `json:"byType"`
+	ByCommand []TaskStat `json:"byCommand"`
+}
+
+type TaskStatsRes struct {
+	Data   TaskStats `json:"data"`
+	Status string    `json:"status"`
+	Error  string    `json:"error"`
+}
@@ -0,0 +1,41 @@
+package schema
+
+import "time"
+
+type UserStatus int
+
+const (
+	UserStatusUndefined UserStatus = iota
+	UserStatusRequested
+	UserStatusActive
+	UserStatusDenied
+	UserStatusRevoked
+)
+
+var userStatusNames = map[UserStatus]string{
+	UserStatusRequested: "requested",
+	UserStatusActive:    "active",
+	UserStatusDenied:    "denied",
+	UserStatusRevoked:   "revoked",
+}
+
+func (s UserStatus) String() string {
+	if name, ok := userStatusNames[s]; ok {
+		return name
+	}
+	return "undefined"
+}
+
+// User is a telegram user known to the bot, only active users have
+// their role.
+type User struct {
+	// Id is the telegram user id
+	Id       int64  `json:"id"`
+	UserName string `json:"userName"`
+	// ChatId is the private chat with the user, 0 when they never wrote
+	ChatId    int64      `json:"chatId"`
+	Role      string     `json:"role"`
+	Status    UserStatus `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
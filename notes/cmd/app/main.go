@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
@@ -60,6 +61,7 @@ func main() {
 	model := domain.NewModel(gc)
 
 	mcore := mcoreclient.NewClient(cfg.MCoreAddr, cfg.MCoreSecret)
+	mcore.SetWorker("notes")
 	ctx, cancel := context.WithCancel(context.Background())
 
 	mcore.ListeningTasks(ctx, schema.TaskTypeNote, model, time.Duration(1*time.Second))
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/logger"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 	"github.com/ishua/a3bot6/notes/internal/clients/gitapi"
@@ -47,6 +48,7 @@ func (m *Model) DoTask(task schema.Task) schema.ReportTaskReq {
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusDone,
 			TextMsg: "note is healthy",
+			MsgKey:  string(i18n.NoteHealthy),
 		}
 	}
 	switch task.TaskData.Tn.Command {
@@ -55,6 +57,7 @@ func (m *Model) DoTask(task schema.Task) schema.ReportTaskReq {
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusDone,
 			TextMsg: "Successfully pulled",
+			MsgKey:  string(i18n.NotePulled),
 		}
 	case schema.TaskNoteReadInbox:
 		return m.readInbox(task.Id)
@@ -77,11 +80,11 @@ func (m *Model) DoTask(task schema.Task) schema.ReportTaskReq {
 }
 
 func (m *Model) add5bx(task schema.Task) schema.ReportTaskReq {
-	return m.addToDiary(task, get5bxPath(), "5BX")
+	return m.addToDiary(task, get5bxPath(task.TaskData.Settings.Now()), "5BX")
 }
 
 func (m *Model) addEntry(task schema.Task) schema.ReportTaskReq {
-	return m.addToDiary(task, getEntryPath(), "entry")
+	return m.addToDiary(task, getEntryPath(task.TaskData.Settings.Now()), "entry")
 }
 
 func (m *Model) addToDiary(task schema.Task, filePath string, label string) schema.ReportTaskReq {
@@ -91,6 +94,7 @@ func (m *Model) addToDiary(task schema.Task, filePath string, label string) sche
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusError,
 			TextMsg: "add text is empty",
+			MsgKey:  string(i18n.NoteEmpty),
 		}
 	}
 	diaryRows, err := m.readFile(filePath)
@@ -103,7 +107,7 @@ func (m *Model) addToDiary(task schema.Task, filePath string, label string) sche
 	}
 	newStrings := []string{}
 
-	h2 := "## " + time.Now().Format("0201")
+	h2 := "## " + task.TaskData.Settings.Now().Format("0201")
 	if len(diaryRows) == 0 || isH2NotExist(h2, diaryRows) {
 		newStrings = append(newStrings, h2)
 	}
@@ -131,6 +135,8 @@ func (m *Model) addToDiary(task schema.Task, filePath string, label string) sche
 		TaskId:  task.Id,
 		Status:  schema.TaskStatusDone,
 		TextMsg: fmt.Sprintf("text add to %s", label),
+		MsgKey:  string(i18n.NoteAdded),
+		MsgArgs: []string{label},
 	}
 }
 
@@ -150,6 +156,7 @@ func (m *Model) addAddInbox(task schema.Task) schema.ReportTaskReq {
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusError,
 			TextMsg: "add text is empty",
+			MsgKey:  string(i18n.NoteEmpty),
 		}
 	}
 	line := fmt.Sprintf("  - %s", addText)
@@ -175,6 +182,8 @@ func (m *Model) addAddInbox(task schema.Task) schema.ReportTaskReq {
 		TaskId:  task.Id,
 		Status:  schema.TaskStatusDone,
 		TextMsg: "text add to inbox",
+		MsgKey:  string(i18n.NoteAdded),
+		MsgArgs: []string{"inbox"},
 	}
 }
 
@@ -256,9 +265,10 @@ func (m *Model) addWeight(task schema.Task) schema.ReportTaskReq {
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusError,
 			TextMsg: "add text is empty",
+			MsgKey:  string(i18n.NoteEmpty),
 		}
 	}
-	line := fmt.Sprintf("%s %s", time.Now().Format("2006-01-02"), addText)
+	line := fmt.Sprintf("%s %s", task.TaskData.Settings.Now().Format("2006-01-02"), addText)
 	err := m.addRowToFile(getWeightPath(), []string{line})
 	if err != nil {
 		return schema.ReportTaskReq{
@@ -281,6 +291,7 @@ func (m *Model) addWeight(task schema.Task) schema.ReportTaskReq {
 		TaskId:  task.Id,
 		Status:  schema.TaskStatusDone,
 		TextMsg: "weight added",
+		MsgKey:  string(i18n.NoteWeightAdded),
 	}
 }
 
@@ -291,9 +302,10 @@ func (m *Model) addBloodPressure(task schema.Task) schema.ReportTaskReq {
 			TaskId:  task.Id,
 			Status:  schema.TaskStatusError,
 			TextMsg: "add text is empty",
+			MsgKey:  string(i18n.NoteEmpty),
 		}
 	}
-	line := fmt.Sprintf("%s %s", time.Now().Format("2006-01-02"), addText)
+	line := fmt.Sprintf("%s %s", task.TaskData.Settings.Now().Format("2006-01-02"), addText)
 	err := m.addRowToFile(getBPPath(), []string{line})
 	if err != nil {
 		return schema.ReportTaskReq{
@@ -316,17 +328,18 @@ func (m *Model) addBloodPressure(task schema.Task) schema.ReportTaskReq {
 		TaskId:  task.Id,
 		Status:  schema.TaskStatusDone,
 		TextMsg: "bp added",
+		MsgKey:  string(i18n.NoteBPAdded),
 	}
 }
 
-func get5bxPath() string {
-	now := time.Now()
+// get5bxPath and getEntryPath name the file of the quarter of now,
+// the date of the user.
+func get5bxPath(now time.Time) string {
 	quarter := (int(now.Month())-1)/3 + 1
 	return fmt.Sprintf("Diary/5BX %d%02d.markdown", now.Year(), quarter)
 }
 
-func getEntryPath() string {
-	now := time.Now()
+func getEntryPath(now time.Time) string {
 	quarter := (int(now.Month())-1)/3 + 1
 	return fmt.Sprintf("Diary/entry %d%02d.markdown", now.Year(), quarter)
 }
@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/ishua/a3bot6/mcore/pkg/i18n"
 	"github.com/ishua/a3bot6/mcore/pkg/mcoreclient"
 	"github.com/ishua/a3bot6/mcore/pkg/schema"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
+	"unicode"
 )
 
 type MyConfig struct {
@@ -45,6 +50,7 @@ func main() {
 	}
 
 	mcore := mcoreclient.NewClient(cfg.MCoreAddr, cfg.TBotSecret)
+	mcore.SetWorker("tbot")
 	tgClient := newTgClient(bot, mcore)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -101,8 +107,12 @@ func (tg *tgClient) DoTask(task schema.Task) schema.ReportTaskReq {
 	msg := tgbotapi.NewMessage(task.TaskData.Msg.ChatId, task.TaskData.Msg.Text)
 	msg.ParseMode = "html"
 	msg.ReplyToMessageID = task.TaskData.Msg.ReplyMessageId
+	msg.DisableNotification = task.TaskData.Settings.Quiet(time.Now())
+	if len(task.TaskData.Msg.Buttons) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(task.TaskData.Msg.Buttons)
+	}
 
-	_, err := tg.bot.Send(msg)
+	sent, err := tg.bot.Send(msg)
 	if err != nil {
 		return schema.ReportTaskReq{
 			TaskId:  task.Id,
@@ -111,9 +121,10 @@ func (tg *tgClient) DoTask(task schema.Task) schema.ReportTaskReq {
 		}
 	}
 	return schema.ReportTaskReq{
-		TaskId:  task.Id,
-		Status:  schema.TaskStatusDone,
-		TextMsg: "message sent",
+		TaskId:    task.Id,
+		Status:    schema.TaskStatusDone,
+		TextMsg:   "message sent",
+		MessageId: sent.MessageID,
 	}
 }
 
@@ -128,10 +139,18 @@ func (tg *tgClient) ListeningTg(ctx context.Context) {
 			select {
 			case update := <-updates:
 				{
+					if update.CallbackQuery != nil {
+						tg.callback(update.CallbackQuery)
+						continue
+					}
 					if update.Message == nil {
 						continue
 					}
-					if update.Message.Chat == nil {
+					if update.Message.Chat == nil || update.Message.From == nil {
+						continue
+					}
+					text, caption, ok := tg.addressed(update.Message)
+					if !ok {
 						continue
 					}
 					var fileUrl string
@@ -144,37 +163,34 @@ func (tg *tgClient) ListeningTg(ctx context.Context) {
 						}
 					}
 					quickMsg, err := tg.mcore.AddMsg(schema.Message{
-						UserName:         update.Message.Chat.UserName,
+						UserId:           update.Message.From.ID,
+						UserName:         update.Message.From.UserName,
 						MessageId:        update.Message.MessageID,
 						ReplyToMessageID: getReplyId(update),
 						ChatId:           update.Message.Chat.ID,
-						Text:             update.Message.Text,
-						Caption:          update.Message.Caption,
+						ChatType:         update.Message.Chat.Type,
+						Text:             text,
+						Caption:          caption,
 						FileUrl:          fileUrl,
 						Type:             0,
+						LanguageCode:     update.Message.From.LanguageCode,
 					})
 
-					if quickMsg.Error != "" {
+					if err != nil {
+						log.Printf("tg addMsg: %s", err.Error())
 						tg.DoTask(schema.Task{
 							Type: schema.TaskTypeMsg,
 							TaskData: schema.TaskData{
 								Msg: schema.TaskMsg{
 									ChatId:         update.Message.Chat.ID,
-									Text:           fmt.Sprintf("something went wrong %s", err.Error()),
-									ReplyMessageId: getReplyId(update),
+									Text:           errorText(err, update.Message.From.LanguageCode),
+									ReplyMessageId: update.Message.MessageID,
 								},
 							},
 						})
 						continue
 					}
-					if quickMsg.Data.ChatId != 0 {
-						tg.DoTask(schema.Task{
-							Type: schema.TaskTypeMsg,
-							TaskData: schema.TaskData{
-								Msg: quickMsg.Data,
-							},
-						})
-					}
+					tg.quickReply(quickMsg)
 				}
 
 			case <-ctx.Done():
@@ -188,6 +204,140 @@ func (tg *tgClient) ListeningTg(ctx context.Context) {
 	}()
 }
 
+// quickReply sends the immediate answer of mcore, replies to it continue the dialog.
+func (tg *tgClient) quickReply(quickMsg schema.AddMsgReq) {
+	if quickMsg.Data.ChatId == 0 {
+		return
+	}
+	res := tg.DoTask(schema.Task{
+		Type: schema.TaskTypeMsg,
+		TaskData: schema.TaskData{
+			Msg: quickMsg.Data,
+		},
+	})
+	if quickMsg.DialogId == 0 || res.MessageId == 0 {
+		return
+	}
+	_, err := tg.mcore.AddBotMsg(schema.BotMsgReq{
+		DialogId:  quickMsg.DialogId,
+		ChatId:    quickMsg.Data.ChatId,
+		MessageId: res.MessageId,
+	})
+	if err != nil {
+		log.Printf("tg botMsg: %s", err.Error())
+	}
+}
+
+// callback forwards a pressed button to mcore, takes the keyboard off
+// the answered message and sends the answer.
+func (tg *tgClient) callback(cq *tgbotapi.CallbackQuery) {
+	if cq.Message == nil || cq.Message.Chat == nil || cq.From == nil {
+		var languageCode string
+		if cq.From != nil {
+			languageCode = cq.From.LanguageCode
+		}
+		lang, _ := i18n.Parse(languageCode)
+		_, err := tg.bot.Request(tgbotapi.NewCallback(cq.ID, i18n.T(lang, i18n.MessageTooOld)))
+		if err != nil {
+			log.Printf("tg answer callback: %s", err.Error())
+		}
+		return
+	}
+	chatId := cq.Message.Chat.ID
+	quickMsg, err := tg.mcore.AddCallback(schema.CallbackReq{
+		ChatId:       chatId,
+		UserId:       cq.From.ID,
+		UserName:     cq.From.UserName,
+		MessageId:    cq.Message.MessageID,
+		Data:         cq.Data,
+		LanguageCode: cq.From.LanguageCode,
+	})
+	if err != nil {
+		log.Printf("tg callback: %s", err.Error())
+		_, err = tg.bot.Request(tgbotapi.NewCallback(cq.ID, errorText(err, cq.From.LanguageCode)))
+		if err != nil {
+			log.Printf("tg answer callback: %s", err.Error())
+		}
+		return
+	}
+
+	_, err = tg.bot.Request(tgbotapi.NewCallback(cq.ID, ""))
+	if err != nil {
+		log.Printf("tg answer callback: %s", err.Error())
+	}
+	_, err = tg.bot.Request(tgbotapi.NewEditMessageReplyMarkup(chatId, cq.Message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+	if err != nil {
+		log.Printf("tg remove keyboard: %s", err.Error())
+	}
+	tg.quickReply(quickMsg)
+}
+
+// addressed returns text and caption of a message for the bot. In a group
+// only commands, mentions of the bot and replies to it are for the bot,
+// the mention is cut off.
+func (tg *tgClient) addressed(m *tgbotapi.Message) (string, string, bool) {
+	if m.Chat.Type == schema.ChatTypePrivate {
+		return m.Text, m.Caption, true
+	}
+	text, textOk := stripMention(m.Text, tg.bot.Self.UserName)
+	caption, captionOk := stripMention(m.Caption, tg.bot.Self.UserName)
+	replyToBot := m.ReplyToMessage != nil && m.ReplyToMessage.From != nil && m.ReplyToMessage.From.ID == tg.bot.Self.ID
+	return text, caption, textOk || captionOk || replyToBot
+}
+
+// stripMention tells whether text is aimed at the bot: a command for no
+// other bot or a mention of the bot, which is cut off.
+func stripMention(text, botName string) (string, bool) {
+	t := strings.TrimSpace(text)
+	if strings.HasPrefix(t, "/") {
+		end := strings.IndexFunc(t, unicode.IsSpace)
+		if end < 0 {
+			end = len(t)
+		}
+		at := strings.Index(t[:end], "@")
+		if at < 0 {
+			return text, true
+		}
+		// /cmd@name
+		if !strings.EqualFold(t[at+1:end], botName) {
+			return text, false
+		}
+		return t[:at] + t[end:], true
+	}
+
+	mention := "@" + botName
+	for i := 0; i+len(mention) <= len(t); i++ {
+		if strings.EqualFold(t[i:i+len(mention)], mention) {
+			return strings.TrimSpace(t[:i] + t[i+len(mention):]), true
+		}
+	}
+	return text, false
+}
+
+func inlineKeyboard(buttons [][]schema.Button) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(buttons))
+	for _, row := range buttons {
+		var r []tgbotapi.InlineKeyboardButton
+		for _, b := range row {
+			r = append(r, tgbotapi.NewInlineKeyboardButtonData(b.Text, b.Data))
+		}
+		rows = append(rows, r)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// errorText shows user mistakes as is, mcore has translated them, and
+// hides server faults in the language of the telegram of the user.
+func errorText(err error, languageCode string) string {
+	var se *schema.Error
+	if schema.IsUserError(err) && errors.As(err, &se) {
+		return se.Message
+	}
+	lang, _ := i18n.Parse(languageCode)
+	return i18n.T(lang, i18n.SomethingWrong)
+}
+
 func getReplyId(update tgbotapi.Update) int {
 	var replyMsgId int
 	if update.Message != nil && update.Message.ReplyToMessage != nil {
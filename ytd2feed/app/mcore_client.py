@@ -1,4 +1,5 @@
 import requests
+import socket
 import sys
 
 class McoreClient:
@@ -6,6 +7,7 @@ class McoreClient:
         self.addr = addr
         self.task_type = task_type
         self.secret = secret
+        self.worker = "ytd2feed@" + socket.gethostname()
 
     def health(self) -> bool:
         try:
@@ -25,18 +27,18 @@ class McoreClient:
             'secret': self.secret
         }
         try:
-            r = requests.post(self.addr + "/get-task/", json={'taskType': self.task_type}, headers=headers)
+            r = requests.post(self.addr + "/get-task/", json={'taskType': self.task_type, 'worker': self.worker}, headers=headers)
         except Exception as e:
             print(e)
             return {}
 
+        if r.status_code == 204:
+            return {}
         if r.status_code != 200:
-            print("can't connect to get-task")
+            print("get-task status code:", r.status_code, r.text)
             return {}
 
         res = r.json()
-        if res['status'] == "no tasks":
-            return {}
         if res['status'] != "OK":
             print("something went wrong:",res["error"])
             return {}
@@ -54,7 +56,8 @@ class McoreClient:
         data = {
             "taskId": task_id,
             "status": status,
-            "textMsg": text_msg
+            "textMsg": text_msg,
+            "worker": self.worker
         }
         try:
             r = requests.post(self.addr + "/report-task/", json=data, headers=headers)